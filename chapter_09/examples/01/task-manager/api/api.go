@@ -0,0 +1,247 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-manager/tasks"
+)
+
+// taskRequest is the body of POST /tasks and PATCH /tasks/{id}.
+// For PATCH only the fields present are changed and "due": "" clears the
+// due date; {"status": "completed"} on its own completes the task.
+type taskRequest struct {
+	Description *string   `json:"description"`
+	Priority    *string   `json:"priority"`
+	Due         *string   `json:"due"`
+	Tags        *[]string `json:"tags"`
+	DependsOn   *[]int    `json:"depends_on"`
+	Recurrence  *string   `json:"recurrence"`
+	Status      *string   `json:"status"`
+}
+
+// requestError marks a malformed request
+type requestError struct{ msg string }
+
+func (e requestError) Error() string { return e.msg }
+
+// NewHandler serves the task list under /tasks:
+//
+//	GET    /tasks?status=&priority=&tag=&overdue=true&sort=  list tasks
+//	POST   /tasks                                           add a task
+//	GET    /tasks/{id}                                      show one task
+//	PATCH  /tasks/{id}                                      edit or complete a task
+//	DELETE /tasks/{id}                                      delete a task
+func NewHandler(store *tasks.SafeStore) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := tasks.Filter{
+			Status:   query.Get("status"),
+			Priority: query.Get("priority"),
+			Tag:      query.Get("tag"),
+			SortBy:   query.Get("sort"),
+		}
+		if overdue := query.Get("overdue"); overdue != "" {
+			var err error
+			if filter.Overdue, err = strconv.ParseBool(overdue); err != nil {
+				writeError(w, requestError{fmt.Sprintf("invalid overdue value %q", overdue)})
+				return
+			}
+		}
+		list, err := store.List(filter)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+
+	mux.HandleFunc("POST /tasks", func(w http.ResponseWriter, r *http.Request) {
+		var req taskRequest
+		if err := decode(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		spec, err := req.spec()
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		task, err := store.Add(spec)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/tasks/%d", task.ID))
+		writeJSON(w, http.StatusCreated, task)
+	})
+
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		task, err := store.Get(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	})
+
+	mux.HandleFunc("PATCH /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var req taskRequest
+		if err := decode(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		task, err := patch(store, id, req)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	})
+
+	mux.HandleFunc("DELETE /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if err := store.Delete(id); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// patch either completes the task or edits its fields; doing both in one
+// request could leave the edit saved when completing fails
+func patch(store *tasks.SafeStore, id int, req taskRequest) (tasks.Task, error) {
+	if req.Status != nil {
+		if tasks.NormalizeStatus(*req.Status) != "Completed" {
+			return tasks.Task{}, requestError{fmt.Sprintf("status can only be set to Completed (got %q)", *req.Status)}
+		}
+		if req != (taskRequest{Status: req.Status}) {
+			return tasks.Task{}, requestError{"status cannot be changed together with other fields"}
+		}
+		task, _, err := store.Complete(id)
+		return task, err
+	}
+
+	edit, err := req.edit()
+	if err != nil {
+		return tasks.Task{}, err
+	}
+	return store.Edit(id, edit)
+}
+
+func (req taskRequest) spec() (tasks.TaskSpec, error) {
+	spec := tasks.TaskSpec{}
+	if req.Description != nil {
+		spec.Description = *req.Description
+	}
+	if req.Priority != nil {
+		spec.Priority = *req.Priority
+	}
+	if req.Due != nil {
+		due, err := tasks.ParseDueDate(*req.Due)
+		if err != nil {
+			return spec, requestError{err.Error()}
+		}
+		spec.Due = due
+	}
+	if req.Tags != nil {
+		spec.Tags = *req.Tags
+	}
+	if req.DependsOn != nil {
+		spec.DependsOn = *req.DependsOn
+	}
+	if req.Recurrence != nil {
+		spec.Recurrence = *req.Recurrence
+	}
+	return spec, nil
+}
+
+func (req taskRequest) edit() (tasks.TaskEdit, error) {
+	edit := tasks.TaskEdit{
+		Description: req.Description,
+		Priority:    req.Priority,
+		Tags:        req.Tags,
+		DependsOn:   req.DependsOn,
+		Recurrence:  req.Recurrence,
+	}
+	if req.Due != nil {
+		due, err := tasks.ParseDueDate(*req.Due)
+		if err != nil {
+			return edit, requestError{err.Error()}
+		}
+		edit.Due = due
+		edit.ClearDue = due == nil
+	}
+	return edit, nil
+}
+
+func decode(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return requestError{"invalid JSON body: " + err.Error()}
+	}
+	return nil
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, requestError{fmt.Sprintf("invalid task ID %q", r.PathValue("id"))}
+	}
+	return id, nil
+}
+
+// statusCode maps errors from the tasks package to HTTP statuses
+func statusCode(err error) int {
+	var rerr requestError
+	switch {
+	case errors.As(err, &rerr),
+		errors.Is(err, tasks.ErrInvalidPriority),
+		errors.Is(err, tasks.ErrInvalidStatus),
+		errors.Is(err, tasks.ErrEmptyDescription),
+		errors.Is(err, tasks.ErrInvalidSort),
+		errors.Is(err, tasks.ErrDependencyCycle),
+		errors.Is(err, tasks.ErrUnknownDependency),
+		errors.Is(err, tasks.ErrInvalidRecurrence),
+		errors.Is(err, tasks.ErrNoChanges):
+		return http.StatusBadRequest
+	case errors.Is(err, tasks.ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, tasks.ErrAlreadyCompleted),
+		errors.Is(err, tasks.ErrTaskBlocked):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusCode(err), map[string]string{"error": strings.TrimSpace(err.Error())})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
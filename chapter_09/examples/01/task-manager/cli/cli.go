@@ -0,0 +1,576 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"task-manager/api"
+	"task-manager/tasks"
+	"time"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK       = 0 // command succeeded
+	ExitError    = 1 // command failed (I/O, storage, ...)
+	ExitUsage    = 2 // bad subcommand, flag or argument
+	ExitNotFound = 3 // the referenced task does not exist
+	ExitNoChange = 4 // nothing to do, e.g. task already completed
+	ExitBadInput = 5 // an input file has invalid rows
+	ExitBlocked  = 6 // the task still waits on unfinished dependencies
+)
+
+const usage = `Usage: task-manager [command] [flags]
+
+Without a command the interactive menu is started.
+
+Commands:
+  add --desc TEXT --priority High|Medium|Low [--due YYYY-MM-DD] [--tags a,b] [--depends 1,2]
+      [--repeat RULE]                          add a task
+  list [--status S] [--priority P] [--tag T] [--overdue]
+       [--sort id|priority|due|next] [--format table|csv|json]
+  next [--format table|csv|json]               unfinished tasks in the order they can be done
+  done ID                                      mark a task as completed
+  edit ID [--desc TEXT] [--priority P] [--due YYYY-MM-DD|--clear-due] [--tags a,b] [--depends 1,2]
+       [--repeat RULE]
+                                               change fields of a task
+  delete ID                                    remove a task
+  undo [-n N]                                  revert the last N changes (default 1)
+  history [--format table|csv|json] [--out FILE]
+                                               list or export the change log
+  export [--csv|--json] [--out FILE]           write tasks to a file ("-" for stdout)
+  import [--csv|--json] [--mode merge|replace] FILE
+                                               read tasks written by export
+  preview (ID | --rule RULE [--from YYYY-MM-DD]) [-n N]
+                                               show the next N due dates of a repeating task
+  serve [--addr :8080]                         serve the REST API (GET/POST/PATCH/DELETE /tasks)
+  help                                         show this message
+
+Repeat rules: daily, weekly, weekly:mon,thu, every 3 days, cron:0 9 * * 1-5
+Completing a repeating task adds its next occurrence as a new task.
+`
+
+// Run executes one subcommand and returns the process exit code
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return ExitUsage
+	}
+
+	var err error
+	switch args[0] {
+	case "add":
+		err = runAdd(args[1:], stdout)
+	case "list":
+		err = runList(args[1:], stdout)
+	case "next":
+		err = runNext(args[1:], stdout)
+	case "done":
+		err = runDone(args[1:], stdout)
+	case "edit":
+		err = runEdit(args[1:], stdout)
+	case "delete":
+		err = runDelete(args[1:], stdout)
+	case "undo":
+		err = runUndo(args[1:], stdout)
+	case "history":
+		err = runHistory(args[1:], stdout)
+	case "export":
+		err = runExport(args[1:], stdout)
+	case "import":
+		err = runImport(args[1:], stdout)
+	case "preview":
+		err = runPreview(args[1:], stdout)
+	case "serve":
+		err = runServe(args[1:], stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	default:
+		err = usageError{fmt.Sprintf("unknown command %q", args[0])}
+	}
+
+	if err == nil {
+		return ExitOK
+	}
+	fmt.Fprintln(stderr, "Error:", err)
+	return exitCode(err)
+}
+
+// usageError marks mistakes in how the command was invoked
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func exitCode(err error) int {
+	var uerr usageError
+	var ierr *tasks.ImportError
+	switch {
+	case errors.As(err, &uerr),
+		errors.Is(err, tasks.ErrInvalidPriority),
+		errors.Is(err, tasks.ErrInvalidStatus),
+		errors.Is(err, tasks.ErrEmptyDescription),
+		errors.Is(err, tasks.ErrInvalidSort),
+		errors.Is(err, tasks.ErrInvalidImportMode),
+		errors.Is(err, tasks.ErrDependencyCycle),
+		errors.Is(err, tasks.ErrUnknownDependency),
+		errors.Is(err, tasks.ErrInvalidRecurrence):
+		return ExitUsage
+	case errors.Is(err, tasks.ErrTaskBlocked):
+		return ExitBlocked
+	case errors.As(err, &ierr):
+		return ExitBadInput
+	case errors.Is(err, tasks.ErrTaskNotFound):
+		return ExitNotFound
+	case errors.Is(err, tasks.ErrAlreadyCompleted),
+		errors.Is(err, tasks.ErrNoChanges),
+		errors.Is(err, tasks.ErrNothingToUndo):
+		return ExitNoChange
+	default:
+		return ExitError
+	}
+}
+
+// newFlagSet returns a flag set that reports errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return usageError{fmt.Sprintf("%s: see 'task-manager help'", fs.Name())}
+		}
+		return usageError{fmt.Sprintf("%s: %v", fs.Name(), err)}
+	}
+	return nil
+}
+
+func runAdd(args []string, stdout io.Writer) error {
+	fs := newFlagSet("add")
+	desc := fs.String("desc", "", "task description")
+	priority := fs.String("priority", "", "task priority (High, Medium, Low)")
+	due := fs.String("due", "", "due date (YYYY-MM-DD)")
+	tags := fs.String("tags", "", "comma separated tags")
+	depends := fs.String("depends", "", "comma separated IDs of tasks that must be completed first")
+	repeat := fs.String("repeat", "", "recurrence rule, e.g. daily or weekly:mon,thu")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	dueDate, err := tasks.ParseDueDate(*due)
+	if err != nil {
+		return usageError{"add: " + err.Error()}
+	}
+	deps, err := tasks.ParseIDs(*depends)
+	if err != nil {
+		return usageError{"add: " + err.Error()}
+	}
+
+	task, err := tasks.CreateTask(tasks.TaskSpec{
+		Description: *desc,
+		Priority:    *priority,
+		Due:         dueDate,
+		Tags:        tasks.ParseTags(*tags),
+		DependsOn:   deps,
+		Recurrence:  *repeat,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, task.ID)
+	return nil
+}
+
+func runList(args []string, stdout io.Writer) error {
+	fs := newFlagSet("list")
+	status := fs.String("status", "", "only tasks with this status (Pending, Blocked, Completed)")
+	priority := fs.String("priority", "", "only tasks with this priority (High, Medium, Low)")
+	tag := fs.String("tag", "", "only tasks carrying this tag")
+	overdue := fs.Bool("overdue", false, "only unfinished tasks past their due date")
+	sortBy := fs.String("sort", "id", "order: id, priority, due or next")
+	format := fs.String("format", "table", "output format: table, csv or json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	list, err := tasks.ListTasks(tasks.Filter{
+		Status:   *status,
+		Priority: *priority,
+		Tag:      *tag,
+		Overdue:  *overdue,
+		SortBy:   *sortBy,
+	})
+	if err != nil {
+		return err
+	}
+
+	return writeTasks(stdout, list, *format, "list")
+}
+
+func runNext(args []string, stdout io.Writer) error {
+	fs := newFlagSet("next")
+	format := fs.String("format", "table", "output format: table, csv or json")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	list, err := tasks.ListTasks(tasks.Filter{SortBy: "next"})
+	if err != nil {
+		return err
+	}
+	unfinished := list[:0]
+	for _, task := range list {
+		if task.Status != "Completed" {
+			unfinished = append(unfinished, task)
+		}
+	}
+	return writeTasks(stdout, unfinished, *format, "next")
+}
+
+func writeTasks(w io.Writer, list []tasks.Task, format, command string) error {
+	switch format {
+	case "table":
+		for _, task := range list {
+			fmt.Fprintln(w, tasks.FormatTask(task))
+		}
+		return nil
+	case "csv":
+		return tasks.WriteCSV(w, list)
+	case "json":
+		return tasks.WriteJSON(w, list)
+	default:
+		return usageError{fmt.Sprintf("%s: unknown format %q", command, format)}
+	}
+}
+
+func runDone(args []string, stdout io.Writer) error {
+	fs := newFlagSet("done")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	next, err := tasks.CompleteTask(id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Task %d marked as completed.\n", id)
+	if next != nil {
+		fmt.Fprintf(stdout, "Next occurrence added as task %d, due %s.\n", next.ID, tasks.FormatDueDate(next.Due))
+	}
+	return nil
+}
+
+func runEdit(args []string, stdout io.Writer) error {
+	fs := newFlagSet("edit")
+	desc := fs.String("desc", "", "new description")
+	priority := fs.String("priority", "", "new priority (High, Medium, Low)")
+	due := fs.String("due", "", "new due date (YYYY-MM-DD)")
+	clearDue := fs.Bool("clear-due", false, "remove the due date")
+	tags := fs.String("tags", "", `new comma separated tags ("" clears them)`)
+	depends := fs.String("depends", "", `IDs this task depends on ("" clears them)`)
+	repeat := fs.String("repeat", "", `recurrence rule ("" stops repeating)`)
+	if err := parseFlags(fs, interspersed(fs, args)); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	// Only flags given on the command line are changed.
+	var edit tasks.TaskEdit
+	var dueErr, dependsErr error
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "desc":
+			edit.Description = desc
+		case "priority":
+			edit.Priority = priority
+		case "due":
+			edit.Due, dueErr = tasks.ParseDueDate(*due)
+		case "clear-due":
+			edit.ClearDue = *clearDue
+		case "tags":
+			parsed := tasks.ParseTags(*tags)
+			edit.Tags = &parsed
+		case "depends":
+			var deps []int
+			deps, dependsErr = tasks.ParseIDs(*depends)
+			edit.DependsOn = &deps
+		case "repeat":
+			edit.Recurrence = repeat
+		}
+	})
+	if dueErr != nil {
+		return usageError{"edit: " + dueErr.Error()}
+	}
+	if dependsErr != nil {
+		return usageError{"edit: " + dependsErr.Error()}
+	}
+
+	task, err := tasks.EditTask(id, edit)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, tasks.FormatTask(task))
+	return nil
+}
+
+func runDelete(args []string, stdout io.Writer) error {
+	fs := newFlagSet("delete")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	id, err := parseID(fs)
+	if err != nil {
+		return err
+	}
+
+	if err := tasks.DeleteTask(id); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Task %d deleted.\n", id)
+	return nil
+}
+
+func runServe(args []string, stdout io.Writer) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usageError{"serve takes no arguments"}
+	}
+
+	fmt.Fprintf(stdout, "Serving tasks on %s\n", *addr)
+	return http.ListenAndServe(*addr, api.NewHandler(tasks.Default))
+}
+
+func runUndo(args []string, stdout io.Writer) error {
+	fs := newFlagSet("undo")
+	n := fs.Int("n", 1, "number of changes to undo")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usageError{"undo: unexpected arguments"}
+	}
+
+	reverts, err := tasks.Undo(*n)
+	for _, change := range reverts {
+		fmt.Fprintln(stdout, change.Summary)
+	}
+	return err
+}
+
+func runHistory(args []string, stdout io.Writer) error {
+	fs := newFlagSet("history")
+	format := fs.String("format", "table", "output format: table, csv or json")
+	out := fs.String("out", "-", `output file, "-" for stdout`)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	changes := tasks.History()
+	var write func(io.Writer, []tasks.Change) error
+	switch *format {
+	case "table":
+		write = writeHistoryTable
+	case "csv":
+		write = tasks.WriteHistoryCSV
+	case "json":
+		write = tasks.WriteHistoryJSON
+	default:
+		return usageError{fmt.Sprintf("history: unknown format %q", *format)}
+	}
+
+	if *out == "-" {
+		return write(stdout, changes)
+	}
+	if err := writeFile(*out, func(w io.Writer) error { return write(w, changes) }); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "History exported to %s successfully.\n", *out)
+	return nil
+}
+
+func writeHistoryTable(w io.Writer, changes []tasks.Change) error {
+	undone := make(map[int]int)
+	for _, change := range changes {
+		if change.Op == "undo" {
+			undone[change.Undoes] = change.Seq
+		}
+	}
+	for _, change := range changes {
+		if _, err := fmt.Fprintln(w, tasks.FormatChange(change, undone)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runPreview(args []string, stdout io.Writer) error {
+	fs := newFlagSet("preview")
+	rule := fs.String("rule", "", "recurrence rule to preview instead of a task")
+	from := fs.String("from", "", "start date for --rule (default today)")
+	n := fs.Int("n", 5, "number of occurrences")
+	if err := parseFlags(fs, interspersed(fs, args)); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return usageError{"preview: -n must be at least 1"}
+	}
+
+	var occurrences []time.Time
+	var err error
+	if *rule != "" {
+		if fs.NArg() != 0 {
+			return usageError{"preview: give either a task ID or --rule"}
+		}
+		start := time.Now()
+		if *from != "" {
+			fromDate, parseErr := tasks.ParseDueDate(*from)
+			if parseErr != nil {
+				return usageError{"preview: " + parseErr.Error()}
+			}
+			start = *fromDate
+		} else {
+			start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		}
+		occurrences, err = tasks.PreviewRecurrence(*rule, start, *n)
+	} else {
+		id, idErr := parseID(fs)
+		if idErr != nil {
+			return idErr
+		}
+		occurrences, err = tasks.PreviewTask(id, *n)
+	}
+
+	for i, due := range occurrences {
+		fmt.Fprintf(stdout, "%d. %s (%s)\n", i+1, tasks.FormatDueDate(&due), due.Weekday())
+	}
+	return err
+}
+
+func runExport(args []string, stdout io.Writer) error {
+	fs := newFlagSet("export")
+	asCSV := fs.Bool("csv", false, "export as CSV (default)")
+	asJSON := fs.Bool("json", false, "export as JSON")
+	out := fs.String("out", "", `output file, "-" for stdout (default tasks.csv or tasks_export.json)`)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *asCSV && *asJSON {
+		return usageError{"export: choose only one of --csv and --json"}
+	}
+
+	write, path := tasks.WriteCSV, "tasks.csv"
+	if *asJSON {
+		write, path = tasks.WriteJSON, "tasks_export.json"
+	}
+	if *out != "" {
+		path = *out
+	}
+
+	list, err := tasks.ListTasks(tasks.Filter{})
+	if err != nil {
+		return err
+	}
+
+	if path == "-" {
+		return write(stdout, list)
+	}
+	if err := writeFile(path, func(w io.Writer) error { return write(w, list) }); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Tasks exported to %s successfully.\n", path)
+	return nil
+}
+
+func runImport(args []string, stdout io.Writer) error {
+	fs := newFlagSet("import")
+	asCSV := fs.Bool("csv", false, "read CSV (default unless the file ends in .json)")
+	asJSON := fs.Bool("json", false, "read JSON")
+	mode := fs.String("mode", string(tasks.ImportMerge), "merge or replace")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *asCSV && *asJSON {
+		return usageError{"import: choose only one of --csv and --json"}
+	}
+	if fs.NArg() != 1 {
+		return usageError{"import: expected exactly one file"}
+	}
+	path := fs.Arg(0)
+
+	var result tasks.ImportResult
+	var err error
+	switch {
+	case *asCSV || *asJSON:
+		file, openErr := os.Open(path)
+		if openErr != nil {
+			return openErr
+		}
+		defer file.Close()
+		if *asJSON {
+			result, err = tasks.ImportJSON(file, tasks.ImportMode(*mode))
+		} else {
+			result, err = tasks.ImportCSV(file, tasks.ImportMode(*mode))
+		}
+	default:
+		result, err = tasks.ImportFile(path, tasks.ImportMode(*mode))
+	}
+	if err != nil {
+		return err
+	}
+
+	tasks.PrintImportResult(stdout, result)
+	return nil
+}
+
+// writeFile creates path and hands it to write, reporting close errors too
+func writeFile(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// interspersed moves a leading positional task ID behind the flags,
+// so both `edit 3 --desc x` and `edit --desc x 3` work.
+func interspersed(fs *flag.FlagSet, args []string) []string {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return append(append([]string(nil), args[1:]...), args[0])
+	}
+	return args
+}
+
+// parseID reads the single positional task ID argument
+func parseID(fs *flag.FlagSet) (int, error) {
+	if fs.NArg() != 1 {
+		return 0, usageError{fmt.Sprintf("%s: expected exactly one task ID", fs.Name())}
+	}
+	id, err := strconv.Atoi(fs.Arg(0))
+	if err != nil {
+		return 0, usageError{fmt.Sprintf("%s: invalid task ID %q", fs.Name(), fs.Arg(0))}
+	}
+	return id, nil
+}
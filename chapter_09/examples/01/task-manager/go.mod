@@ -1,3 +1,5 @@
 module task-manager
 
 go 1.23.3
+
+require github.com/mattn/go-sqlite3 v1.14.24
@@ -2,18 +2,60 @@ package main
 
 import (
     "fmt"
+    "os"
+    "task-manager/cli"
     "task-manager/tasks"
     "task-manager/utils"
 )
 
 func main() {
+    // TASK_STORE picks where tasks are kept: a .db/.sqlite file uses SQLite,
+    // anything else is a JSON file.
+    storePath := os.Getenv("TASK_STORE")
+    if storePath == "" {
+        storePath = "tasks.json"
+    }
+
+    store, err := tasks.OpenStore(storePath)
+    if err != nil {
+        fmt.Println("Error opening task store:", err)
+        os.Exit(1)
+    }
+    if err := tasks.UseStore(store); err != nil {
+        fmt.Println("Error:", err)
+        os.Exit(1)
+    }
+
+    // Any arguments run a single subcommand instead of the menu,
+    // e.g. `task-manager add --desc "Clean the kitchen" --priority high`.
+    if len(os.Args) > 1 {
+        code := cli.Run(os.Args[1:], os.Stdout, os.Stderr)
+        if err := tasks.CloseStore(); err != nil && code == cli.ExitOK {
+            fmt.Fprintln(os.Stderr, "Error:", err)
+            code = cli.ExitError
+        }
+        os.Exit(code)
+    }
+    defer tasks.CloseStore()
+
     for {
         fmt.Println("\nTask Management System")
         fmt.Println("1. Add Task")
         fmt.Println("2. View Tasks")
         fmt.Println("3. Mark Task as Completed")
         fmt.Println("4. Export to CSV")
-        fmt.Println("5. Exit")
+        fmt.Println("5. View Tasks by Priority")
+        fmt.Println("6. View Tasks by Due Date")
+        fmt.Println("7. View Overdue Tasks")
+        fmt.Println("8. Import Tasks")
+        fmt.Println("9. Edit Task")
+        fmt.Println("10. Delete Task")
+        fmt.Println("11. Undo Changes")
+        fmt.Println("12. View Change History")
+        fmt.Println("13. Set Task Dependencies")
+        fmt.Println("14. What Can I Work On Next")
+        fmt.Println("15. Preview Repeating Task")
+        fmt.Println("16. Exit")
         choice := utils.GetIntInput("Choose an option: ")
 
         switch choice {
@@ -26,6 +68,28 @@ func main() {
         case 4:
             tasks.ExportToCSV()
         case 5:
+            tasks.ViewTasksSorted("priority")
+        case 6:
+            tasks.ViewTasksSorted("due")
+        case 7:
+            tasks.ViewOverdueTasks()
+        case 8:
+            tasks.ImportTasks()
+        case 9:
+            tasks.EditTaskPrompt()
+        case 10:
+            tasks.DeleteTaskPrompt()
+        case 11:
+            tasks.UndoPrompt()
+        case 12:
+            tasks.ViewHistory()
+        case 13:
+            tasks.DependencyPrompt()
+        case 14:
+            tasks.ViewNextTasks()
+        case 15:
+            tasks.PreviewPrompt()
+        case 16:
             fmt.Println("Goodbye!")
             return
         default:
@@ -36,5 +100,25 @@ func main() {
 
 /*
 % go mod init test-app
-% go run main.go
+% go get github.com/mattn/go-sqlite3
+% go run main.go                          # tasks kept in tasks.json
+% TASK_STORE=tasks.db go run main.go      # tasks kept in SQLite
+
+% go run main.go add --desc "Clean the kitchen" --priority high
+% go run main.go add --desc "File taxes" --priority medium --due 2025-04-15 --tags home,money
+% go run main.go list --status pending --format json
+% go run main.go list --overdue --sort due
+% go run main.go done 1
+% go run main.go export --json --out -
+% go run main.go import --mode merge tasks.csv
+% go run main.go edit 1 --desc "Clean the kitchen floor" --clear-due
+% go run main.go delete 2
+% go run main.go add --desc "Paint the walls" --priority high --depends 3,4
+% go run main.go next
+% go run main.go add --desc "Water plants" --priority low --due 2025-01-06 --repeat weekly:mon,thu
+% go run main.go preview 5 -n 4
+% go run main.go preview --rule "cron:0 9 * * 1-5" -n 3
+% go run main.go undo -n 2
+% go run main.go history --format csv --out audit.csv
+% echo $?   # 0 ok, 1 error, 2 usage, 3 task not found, 4 nothing to change, 5 invalid input rows, 6 task blocked
 */
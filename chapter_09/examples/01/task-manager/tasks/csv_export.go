@@ -2,13 +2,26 @@ package tasks
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// csvHeader lists the columns written by WriteCSV, in order
+var csvHeader = []string{"ID", "Description", "Status", "Priority", "Due", "Tags", "Created", "Completed", "DependsOn", "Recurrence"}
+
 // ExportToCSV exports the task list to a CSV file
 func ExportToCSV() {
+	list, err := ListTasks(Filter{})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
 	file, err := os.Create("tasks.csv")
 	if err != nil {
 		fmt.Println("Error creating CSV file:", err)
@@ -16,17 +29,47 @@ func ExportToCSV() {
 	}
 	defer file.Close()
 
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	if err := WriteCSV(file, list); err != nil {
+		fmt.Println("Error writing CSV file:", err)
+		return
+	}
+	fmt.Println("Tasks exported to tasks.csv successfully.")
+}
+
+// WriteCSV writes the given tasks as CSV with a header row
+func WriteCSV(w io.Writer, list []Task) error {
+	writer := csv.NewWriter(w)
 
-	writer.Write([]string{"ID", "Description", "Status", "Priority"})
-	for _, task := range tasks {
+	writer.Write(csvHeader)
+	for _, task := range list {
 		writer.Write([]string{
 			strconv.Itoa(task.ID),	// strconv.FormatUint(uint64(task.ID), 10) // base-10
 			task.Description,
 			task.Status,
 			task.Priority,
+			FormatDueDate(task.Due),
+			strings.Join(task.Tags, ","),
+			formatTimestamp(&task.CreatedAt),
+			formatTimestamp(task.CompletedAt),
+			formatIDs(task.DependsOn),
+			task.Recurrence,
 		})
 	}
-	fmt.Println("Tasks exported to tasks.csv successfully.")
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the given tasks as an indented JSON array
+func WriteJSON(w io.Writer, list []Task) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
+
+// formatTimestamp renders t as RFC 3339, or "" when t is unset
+func formatTimestamp(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
 }
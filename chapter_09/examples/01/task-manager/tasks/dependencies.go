@@ -0,0 +1,284 @@
+package tasks
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrDependencyCycle   = errors.New("dependency cycle")
+	ErrUnknownDependency = errors.New("unknown dependency")
+	ErrTaskBlocked       = errors.New("task is blocked by unfinished dependencies")
+)
+
+// ParseIDs reads a comma separated list of task IDs
+func ParseIDs(value string) ([]int, error) {
+	var ids []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.Atoi(field)
+		if err != nil || id < 1 {
+			return nil, fmt.Errorf("invalid task ID %q", field)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// formatIDs renders IDs the way ParseIDs reads them back
+func formatIDs(ids []int) string {
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		fields[i] = strconv.Itoa(id)
+	}
+	return strings.Join(fields, ",")
+}
+
+// validateDependencies checks that task id may depend on deps within list:
+// every dependency must exist, must not be the task itself and must not
+// lead back to it. It returns the dependencies sorted and de-duplicated.
+func validateDependencies(list []Task, id int, deps []int) ([]int, error) {
+	if len(deps) == 0 {
+		return nil, nil
+	}
+
+	unique := make([]int, 0, len(deps))
+	seen := make(map[int]bool)
+	for _, dep := range deps {
+		if seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		if dep == id {
+			return nil, fmt.Errorf("%w: task %d cannot depend on itself", ErrDependencyCycle, id)
+		}
+		if indexIn(list, dep) < 0 {
+			return nil, fmt.Errorf("%w: task %d", ErrUnknownDependency, dep)
+		}
+		unique = append(unique, dep)
+	}
+	sort.Ints(unique)
+
+	if path := findCycle(list, id, unique); path != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, formatPath(path))
+	}
+	return unique, nil
+}
+
+// findCycle returns a dependency path from id back to id if giving id the
+// dependencies deps would close a loop, or nil if it would not.
+func findCycle(list []Task, id int, deps []int) []int {
+	edges := make(map[int][]int, len(list))
+	for _, task := range list {
+		edges[task.ID] = task.DependsOn
+	}
+	edges[id] = deps
+
+	visited := make(map[int]bool)
+	var path []int
+	var walk func(node int) bool
+	walk = func(node int) bool {
+		path = append(path, node)
+		for _, next := range edges[node] {
+			if next == id {
+				path = append(path, id)
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if walk(next) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if walk(id) {
+		return path
+	}
+	return nil
+}
+
+func formatPath(path []int) string {
+	steps := make([]string, len(path))
+	for i, id := range path {
+		steps[i] = strconv.Itoa(id)
+	}
+	return strings.Join(steps, " -> ")
+}
+
+// pendingDependencies lists the dependencies of task that are not completed yet.
+// Dependencies that no longer exist do not block.
+func pendingDependencies(list []Task, task Task) []int {
+	var pending []int
+	for _, dep := range task.DependsOn {
+		if i := indexIn(list, dep); i >= 0 && list[i].Status != "Completed" {
+			pending = append(pending, dep)
+		}
+	}
+	return pending
+}
+
+// refreshBlocked derives Pending/Blocked for every unfinished task in list
+func refreshBlocked(list []Task) {
+	for i, task := range list {
+		if task.Status == "Completed" {
+			continue
+		}
+		if len(pendingDependencies(list, task)) > 0 {
+			list[i].Status = "Blocked"
+		} else {
+			list[i].Status = "Pending"
+		}
+	}
+}
+
+// removeDependency drops id from the dependencies of every task in list and
+// returns a diff for each task it touched
+func removeDependency(list []Task, id int) []Diff {
+	var diffs []Diff
+	for i, task := range list {
+		kept := make([]int, 0, len(task.DependsOn))
+		for _, dep := range task.DependsOn {
+			if dep != id {
+				kept = append(kept, dep)
+			}
+		}
+		if len(kept) == len(task.DependsOn) {
+			continue
+		}
+		if len(kept) == 0 {
+			kept = nil
+		}
+		before := task.clone()
+		list[i].DependsOn = kept
+		diffs = append(diffs, changed(before, list[i]))
+	}
+	return diffs
+}
+
+// topoOrder orders list so every task comes after the tasks it depends on.
+// Among tasks that are free to go next, higher priority, then earlier due
+// date, then lower ID wins. Completed tasks go last, by ID.
+func topoOrder(list []Task) []Task {
+	var open, done []Task
+	for _, task := range list {
+		if task.Status == "Completed" {
+			done = append(done, task)
+		} else {
+			open = append(open, task)
+		}
+	}
+
+	waiting := make(map[int]int, len(open))      // unfinished dependencies left, per task
+	dependents := make(map[int][]int, len(open)) // dependency -> tasks waiting on it
+	byID := make(map[int]Task, len(open))
+	for _, task := range open {
+		byID[task.ID] = task
+	}
+	for _, task := range open {
+		for _, dep := range task.DependsOn {
+			if _, ok := byID[dep]; ok {
+				waiting[task.ID]++
+				dependents[dep] = append(dependents[dep], task.ID)
+			}
+		}
+	}
+
+	ready := &readyQueue{}
+	for _, task := range open {
+		if waiting[task.ID] == 0 {
+			heap.Push(ready, task)
+		}
+	}
+
+	ordered := make([]Task, 0, len(list))
+	for ready.Len() > 0 {
+		task := heap.Pop(ready).(Task)
+		ordered = append(ordered, task)
+		for _, id := range dependents[task.ID] {
+			waiting[id]--
+			if waiting[id] == 0 {
+				heap.Push(ready, byID[id])
+			}
+		}
+	}
+
+	// A cycle can only come from a hand-edited store; keep those tasks rather than drop them.
+	if len(ordered) < len(open) {
+		for _, task := range open {
+			if waiting[task.ID] > 0 {
+				ordered = append(ordered, task)
+			}
+		}
+	}
+
+	sort.SliceStable(done, func(i, j int) bool { return done[i].ID < done[j].ID })
+	return append(ordered, done...)
+}
+
+// readyQueue is a min-heap of tasks in "work on this first" order
+type readyQueue []Task
+
+func (q readyQueue) Len() int { return len(q) }
+func (q readyQueue) Less(i, j int) bool {
+	a, b := q[i], q[j]
+	if PriorityRank(a.Priority) != PriorityRank(b.Priority) {
+		return PriorityRank(a.Priority) < PriorityRank(b.Priority)
+	}
+	switch {
+	case a.Due != nil && b.Due == nil:
+		return true
+	case a.Due == nil && b.Due != nil:
+		return false
+	case a.Due != nil && !a.Due.Equal(*b.Due):
+		return a.Due.Before(*b.Due)
+	}
+	return a.ID < b.ID
+}
+func (q readyQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *readyQueue) Push(x any)   { *q = append(*q, x.(Task)) }
+func (q *readyQueue) Pop() any {
+	old := *q
+	task := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return task
+}
+
+// ViewNextTasks displays unfinished tasks in the order they can be worked on:
+// everything ready now first, then blocked tasks after the work they wait for.
+func ViewNextTasks() {
+	list, err := ListTasks(Filter{SortBy: "next"})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	var ready, blocked []Task
+	for _, task := range list {
+		switch task.Status {
+		case "Pending":
+			ready = append(ready, task)
+		case "Blocked":
+			blocked = append(blocked, task)
+		}
+	}
+
+	printTasks("\nReady to work on:", "Nothing is ready to work on.", ready)
+	if len(blocked) > 0 {
+		at := now()
+		fmt.Println("\nWaiting on other tasks:")
+		for _, task := range blocked {
+			fmt.Printf("%s | Waiting on: %s\n", formatTaskAt(task, at), formatIDs(pendingDependencies(list, task)))
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package tasks
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// Diff is the state of one task before and after a change.
+// Before is nil for an added task, After is nil for a deleted one.
+type Diff struct {
+	Before *Task `json:"before,omitempty"`
+	After  *Task `json:"after,omitempty"`
+}
+
+// Change is one entry of the append-only change log
+type Change struct {
+	Seq     int       `json:"seq"`
+	Time    time.Time `json:"time"`
+	Op      string    `json:"op"` // "add", "edit", "complete", "delete", "import" or "undo"
+	Summary string    `json:"summary"`
+	Diffs   []Diff    `json:"diffs"`
+	Undoes  int       `json:"undoes,omitempty"` // Seq of the change an "undo" reverted
+}
+
+// record stamps change with the next Seq and the current time and appends
+// it to the log; callers commit afterwards
+func (s *SafeStore) record(change Change) Change {
+	change.Seq = 1
+	if len(s.history) > 0 {
+		change.Seq = s.history[len(s.history)-1].Seq + 1
+	}
+	change.Time = now()
+	s.history = append(s.history, change)
+	return change
+}
+
+// added, changed and removed build the Diff for each kind of mutation
+func added(task Task) Diff {
+	after := task.clone()
+	return Diff{After: &after}
+}
+
+func changed(before, after Task) Diff {
+	b, a := before.clone(), after.clone()
+	return Diff{Before: &b, After: &a}
+}
+
+func removed(task Task) Diff {
+	before := task.clone()
+	return Diff{Before: &before}
+}
+
+// TaskIDs lists the tasks touched by the change
+func (c Change) TaskIDs() []int {
+	ids := make([]int, 0, len(c.Diffs))
+	for _, diff := range c.Diffs {
+		if diff.After != nil {
+			ids = append(ids, diff.After.ID)
+		} else if diff.Before != nil {
+			ids = append(ids, diff.Before.ID)
+		}
+	}
+	return ids
+}
+
+// History returns a copy of the change log of Default, oldest first
+func History() []Change {
+	return Default.History()
+}
+
+// undoneBy maps the Seq of every reverted change in list to the undo that reverted it
+func undoneBy(list []Change) map[int]int {
+	undone := make(map[int]int)
+	for _, change := range list {
+		if change.Op == "undo" {
+			undone[change.Undoes] = change.Seq
+		}
+	}
+	return undone
+}
+
+// Undo reverts the last n changes that have not been undone yet, newest first.
+// Each revert is appended to the log as an "undo" change; IDs freed by
+// undoing an add are not handed out again.
+func Undo(n int) ([]Change, error) {
+	return Default.Undo(n)
+}
+
+func (s *SafeStore) undo(n int) ([]Change, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid undo count %d", n)
+	}
+
+	undone := undoneBy(s.history)
+	var targets []Change
+	for i := len(s.history) - 1; i >= 0 && len(targets) < n; i-- {
+		change := s.history[i]
+		if change.Op == "undo" || undone[change.Seq] != 0 {
+			continue
+		}
+		targets = append(targets, change)
+	}
+	if len(targets) == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	// Work on a copy so a change that no longer applies leaves nothing half-undone.
+	working := append([]Task(nil), s.tasks...)
+	inverses := make([][]Diff, len(targets))
+	for i, change := range targets {
+		var err error
+		if working, inverses[i], err = revert(working, change); err != nil {
+			return nil, fmt.Errorf("undo #%d: %w", change.Seq, err)
+		}
+	}
+
+	cp := s.saveCheckpoint()
+	refreshBlocked(working)
+	s.tasks = working
+	reverts := make([]Change, len(targets))
+	for i, change := range targets {
+		reverts[i] = s.record(Change{
+			Op:      "undo",
+			Summary: fmt.Sprintf("undo #%d (%s)", change.Seq, change.Summary),
+			Diffs:   inverses[i],
+			Undoes:  change.Seq,
+		})
+	}
+	if err := s.commit(cp); err != nil {
+		return nil, err
+	}
+	return reverts, nil
+}
+
+// revert applies the inverse of change to list and returns the inverse diffs
+func revert(list []Task, change Change) ([]Task, []Diff, error) {
+	inverse := make([]Diff, 0, len(change.Diffs))
+	for i := len(change.Diffs) - 1; i >= 0; i-- {
+		diff := change.Diffs[i]
+		switch {
+		case diff.Before == nil && diff.After != nil:
+			idx := indexIn(list, diff.After.ID)
+			if idx < 0 {
+				return nil, nil, fmt.Errorf("task %d: %w", diff.After.ID, ErrTaskNotFound)
+			}
+			inverse = append(inverse, removed(list[idx]))
+			list = append(list[:idx:idx], list[idx+1:]...)
+		case diff.Before != nil && diff.After == nil:
+			if indexIn(list, diff.Before.ID) >= 0 {
+				return nil, nil, fmt.Errorf("task %d already exists", diff.Before.ID)
+			}
+			list = insertByID(list, diff.Before.clone())
+			inverse = append(inverse, added(*diff.Before))
+		case diff.Before != nil && diff.After != nil:
+			idx := indexIn(list, diff.After.ID)
+			if idx < 0 {
+				return nil, nil, fmt.Errorf("task %d: %w", diff.After.ID, ErrTaskNotFound)
+			}
+			inverse = append(inverse, changed(list[idx], *diff.Before))
+			list[idx] = diff.Before.clone()
+		}
+	}
+	return list, inverse, nil
+}
+
+func indexIn(list []Task, id int) int {
+	for i, task := range list {
+		if task.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertByID puts task back before the first task with a larger ID
+func insertByID(list []Task, task Task) []Task {
+	idx := sort.Search(len(list), func(i int) bool { return list[i].ID > task.ID })
+	list = append(list, Task{})
+	copy(list[idx+1:], list[idx:])
+	list[idx] = task
+	return list
+}
+
+// FormatChange renders a change log entry as a single line
+func FormatChange(change Change, undone map[int]int) string {
+	line := fmt.Sprintf("#%d | %s | %s | %s",
+		change.Seq, change.Time.Format(time.RFC3339), change.Op, change.Summary)
+	if by := undone[change.Seq]; by != 0 {
+		line += fmt.Sprintf(" (undone by #%d)", by)
+	}
+	return line
+}
+
+// ViewHistory displays the change log, oldest first
+func ViewHistory() {
+	list := History()
+	if len(list) == 0 {
+		fmt.Println("No changes recorded yet.")
+		return
+	}
+
+	fmt.Println("\nChange History:")
+	undone := undoneBy(list)
+	for _, change := range list {
+		fmt.Println(FormatChange(change, undone))
+	}
+}
+
+// WriteHistoryJSON writes the full change log, including task snapshots, as JSON
+func WriteHistoryJSON(w io.Writer, list []Change) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(list)
+}
+
+// WriteHistoryCSV writes one summary row per change
+func WriteHistoryCSV(w io.Writer, list []Change) error {
+	writer := csv.NewWriter(w)
+
+	writer.Write([]string{"Seq", "Time", "Op", "Summary", "TaskIDs", "Undoes"})
+	for _, change := range list {
+		ids := make([]string, 0, len(change.Diffs))
+		for _, id := range change.TaskIDs() {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		undoes := ""
+		if change.Undoes != 0 {
+			undoes = strconv.Itoa(change.Undoes)
+		}
+		writer.Write([]string{
+			strconv.Itoa(change.Seq),
+			change.Time.Format(time.RFC3339),
+			change.Op,
+			change.Summary,
+			strings.Join(ids, ","),
+			undoes,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
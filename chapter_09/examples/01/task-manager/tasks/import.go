@@ -0,0 +1,433 @@
+package tasks
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"task-manager/utils"
+	"time"
+)
+
+// ImportMode decides what happens to the existing tasks on import
+type ImportMode string
+
+const (
+	ImportMerge   ImportMode = "merge"   // keep existing tasks, add the imported ones
+	ImportReplace ImportMode = "replace" // drop existing tasks, keep only the imported ones
+)
+
+var ErrInvalidImportMode = errors.New("invalid import mode, choose merge or replace")
+
+// RowError is a problem with one record of an import file
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// ImportError lists every invalid record; nothing is imported when it is returned
+type ImportError struct {
+	Rows []RowError
+}
+
+func (e *ImportError) Error() string {
+	lines := make([]string, len(e.Rows))
+	for i, row := range e.Rows {
+		lines[i] = row.Error()
+	}
+	return fmt.Sprintf("%d invalid row(s):\n  %s", len(e.Rows), strings.Join(lines, "\n  "))
+}
+
+// Remap records an imported task whose ID was already taken
+type Remap struct {
+	Line int
+	From int
+	To   int
+}
+
+// ImportResult summarises a successful import
+type ImportResult struct {
+	Imported int
+	Replaced int // existing tasks dropped in replace mode
+	Remapped []Remap
+}
+
+// importedRow is a parsed record waiting to be validated
+type importedRow struct {
+	line int
+	task Task
+}
+
+// ImportCSV reads tasks in the format written by ExportToCSV into Default.
+// Columns are matched by header name, so older exports without the
+// Due/Tags/Created/Completed columns import too.
+func ImportCSV(r io.Reader, mode ImportMode) (ImportResult, error) {
+	return Default.ImportCSV(r, mode)
+}
+
+// ImportCSV reads tasks in the format written by ExportToCSV, see the
+// ImportCSV function
+func (s *SafeStore) ImportCSV(r io.Reader, mode ImportMode) (ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return ImportResult{}, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"description", "status", "priority"} {
+		if _, ok := columns[required]; !ok {
+			return ImportResult{}, fmt.Errorf("CSV header is missing the %q column", required)
+		}
+	}
+
+	var rows []importedRow
+	var rowErrors []RowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.Line, Err: parseErr.Err})
+				continue
+			}
+			return ImportResult{}, err
+		}
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		task, err := taskFromCSV(field)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, importedRow{line: line, task: task})
+	}
+
+	return s.importRows(rows, rowErrors, mode)
+}
+
+func taskFromCSV(field func(string) string) (Task, error) {
+	task := Task{
+		Description: field("description"),
+		Status:      field("status"),
+		Priority:    field("priority"),
+		Tags:        ParseTags(field("tags")),
+	}
+
+	var err error
+	if id := field("id"); id != "" {
+		if task.ID, err = strconv.Atoi(id); err != nil {
+			return task, fmt.Errorf("invalid ID %q", id)
+		}
+	}
+	if task.Due, err = ParseDueDate(field("due")); err != nil {
+		return task, err
+	}
+	if task.DependsOn, err = ParseIDs(field("dependson")); err != nil {
+		return task, fmt.Errorf("invalid DependsOn: %w", err)
+	}
+	task.Recurrence = field("recurrence")
+	created, err := parseTimestamp(field("created"))
+	if err != nil {
+		return task, fmt.Errorf("invalid Created: %w", err)
+	}
+	if created != nil {
+		task.CreatedAt = *created
+	}
+	if task.CompletedAt, err = parseTimestamp(field("completed")); err != nil {
+		return task, fmt.Errorf("invalid Completed: %w", err)
+	}
+	return task, nil
+}
+
+// ImportJSON reads a JSON array of tasks, as written by `export --json`, into Default
+func ImportJSON(r io.Reader, mode ImportMode) (ImportResult, error) {
+	return Default.ImportJSON(r, mode)
+}
+
+// ImportJSON reads a JSON array of tasks, see the ImportJSON function
+func (s *SafeStore) ImportJSON(r io.Reader, mode ImportMode) (ImportResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return ImportResult{}, errors.New("JSON import must be an array of tasks")
+	}
+
+	var rows []importedRow
+	var rowErrors []RowError
+	for decoder.More() {
+		line := lineAt(data, decoder.InputOffset())
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return ImportResult{}, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var task Task
+		if err := json.Unmarshal(raw, &task); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, importedRow{line: line, task: task})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return ImportResult{}, fmt.Errorf("line %d: %w", lineAt(data, decoder.InputOffset()), err)
+	}
+
+	return s.importRows(rows, rowErrors, mode)
+}
+
+// lineAt returns the 1-based line of the next value after offset,
+// skipping the whitespace and comma separating array elements.
+func lineAt(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && strings.IndexByte(" \t\r\n,", data[i]) >= 0 {
+		i++
+	}
+	return bytes.Count(data[:i], []byte("\n")) + 1
+}
+
+// importRows validates every row and, only if all of them are valid,
+// applies them to the task list according to mode. It takes the lock
+// itself, so the file is read and parsed without holding it.
+func (s *SafeStore) importRows(rows []importedRow, rowErrors []RowError, mode ImportMode) (ImportResult, error) {
+	if mode != ImportMerge && mode != ImportReplace {
+		return ImportResult{}, ErrInvalidImportMode
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range rows {
+		if err := validateImported(&rows[i].task); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: rows[i].line, Err: err})
+		}
+	}
+	if len(rowErrors) > 0 {
+		sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Line < rowErrors[j].Line })
+		return ImportResult{}, &ImportError{Rows: rowErrors}
+	}
+
+	var result ImportResult
+	var diffs []Diff
+	merged := s.tasks
+	if mode == ImportReplace {
+		result.Replaced = len(s.tasks)
+		for _, task := range s.tasks {
+			diffs = append(diffs, removed(task))
+		}
+		merged = make([]Task, 0, len(rows))
+	}
+
+	taken := make(map[int]bool)
+	for _, task := range merged {
+		taken[task.ID] = true
+	}
+
+	// IDs are only handed out past everything already used, so a remapped
+	// task never collides with a later row that kept its own ID.
+	next := s.nextID
+	for _, row := range rows {
+		if row.task.ID >= next {
+			next = row.task.ID + 1
+		}
+	}
+
+	merged = append([]Task(nil), merged...)
+	newIDs := make(map[int]int) // ID in the file -> ID after import
+	imported := make([]importedRow, 0, len(rows))
+	for _, row := range rows {
+		task := row.task.clone()
+		fileID := task.ID
+		if task.ID <= 0 || taken[task.ID] {
+			newID := next
+			next++
+			if task.ID > 0 {
+				result.Remapped = append(result.Remapped, Remap{Line: row.line, From: task.ID, To: newID})
+			}
+			task.ID = newID
+		}
+		if _, seen := newIDs[fileID]; fileID > 0 && !seen {
+			newIDs[fileID] = task.ID
+		}
+		taken[task.ID] = true
+		merged = insertByID(merged, task)
+		imported = append(imported, importedRow{line: row.line, task: task})
+	}
+
+	// Dependencies point at IDs as written in the file; follow any remapping,
+	// then make sure every link resolves and no cycle was introduced.
+	for i := range imported {
+		task := &imported[i].task
+		for j, dep := range task.DependsOn {
+			if newID, ok := newIDs[dep]; ok {
+				task.DependsOn[j] = newID
+			}
+		}
+		merged[indexIn(merged, task.ID)].DependsOn = task.DependsOn
+	}
+	for _, row := range imported {
+		deps, err := validateDependencies(merged, row.task.ID, row.task.DependsOn)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: row.line, Err: err})
+			continue
+		}
+		merged[indexIn(merged, row.task.ID)].DependsOn = deps
+	}
+	if len(rowErrors) > 0 {
+		return ImportResult{}, &ImportError{Rows: rowErrors}
+	}
+
+	refreshBlocked(merged)
+	for _, row := range imported {
+		diffs = append(diffs, added(merged[indexIn(merged, row.task.ID)]))
+		result.Imported++
+	}
+
+	cp := s.saveCheckpoint()
+	s.tasks = merged
+	s.nextID = next
+	s.record(Change{
+		Op:      "import",
+		Summary: fmt.Sprintf("import (%s): %d added, %d replaced", mode, result.Imported, result.Replaced),
+		Diffs:   diffs,
+	})
+	if err := s.commit(cp); err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+// validateImported checks and normalises one imported task
+func validateImported(task *Task) error {
+	task.Description = strings.TrimSpace(task.Description)
+	if task.Description == "" {
+		return ErrEmptyDescription
+	}
+	if task.ID < 0 {
+		return fmt.Errorf("invalid ID %d", task.ID)
+	}
+
+	priority := NormalizePriority(task.Priority)
+	if priority == "" {
+		return fmt.Errorf("%w (got %q)", ErrInvalidPriority, task.Priority)
+	}
+	task.Priority = priority
+
+	status := NormalizeStatus(task.Status)
+	if status == "" {
+		return fmt.Errorf("%w (got %q)", ErrInvalidStatus, task.Status)
+	}
+	task.Status = status
+
+	recurrence, err := normalizeRecurrence(task.Recurrence)
+	if err != nil {
+		return err
+	}
+	task.Recurrence = recurrence
+
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = now()
+	}
+	if status == "Completed" && task.CompletedAt == nil {
+		completedAt := task.CreatedAt
+		task.CompletedAt = &completedAt
+	}
+	if status != "Completed" {
+		task.CompletedAt = nil
+	}
+	return nil
+}
+
+func parseTimestamp(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ImportFile imports path as CSV or JSON depending on its extension
+func ImportFile(path string, mode ImportMode) (ImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ImportResult{}, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ImportJSON(file, mode)
+	}
+	return ImportCSV(file, mode)
+}
+
+// ImportTasks prompts for a file and import mode, then imports it
+func ImportTasks() {
+	path := utils.GetStringInput("Enter the file to import (.csv or .json): ")
+
+	mode := ImportMerge
+	for {
+		answer := strings.ToLower(utils.GetStringInput("Merge with or replace the current tasks? (merge, replace) [merge]: "))
+		if answer == "" || answer == string(ImportMerge) {
+			break
+		}
+		if answer == string(ImportReplace) {
+			mode = ImportReplace
+			break
+		}
+		fmt.Println("Invalid mode. Please choose merge or replace.")
+	}
+
+	result, err := ImportFile(path, mode)
+	if err != nil {
+		fmt.Println("Import failed:", err)
+		return
+	}
+	PrintImportResult(os.Stdout, result)
+}
+
+// PrintImportResult writes a short summary of an import
+func PrintImportResult(w io.Writer, result ImportResult) {
+	if result.Replaced > 0 {
+		fmt.Fprintf(w, "Replaced %d existing task(s).\n", result.Replaced)
+	}
+	for _, remap := range result.Remapped {
+		fmt.Fprintf(w, "Line %d: task ID %d already in use, imported as %d.\n", remap.Line, remap.From, remap.To)
+	}
+	fmt.Fprintf(w, "Imported %d task(s).\n", result.Imported)
+}
@@ -0,0 +1,76 @@
+package tasks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// JSONStore keeps the task list in a single JSON file
+type JSONStore struct {
+	path string
+}
+
+// NewJSONStore returns a store backed by the JSON file at path.
+// The file is created on the first save.
+func NewJSONStore(path string) *JSONStore {
+	return &JSONStore{path: path}
+}
+
+// Load reads the snapshot from disk; a missing file is an empty task list
+func (s *JSONStore) Load() (Snapshot, error) {
+	var snapshot Snapshot
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return snapshot, nil
+	}
+	if err != nil {
+		return snapshot, fmt.Errorf("reading %s: %w", s.path, err)
+	}
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+	return snapshot, nil
+}
+
+// Save writes the snapshot to a temp file and renames it over the old one,
+// so a crash mid-write never leaves a truncated file behind.
+func (s *JSONStore) Save(snapshot Snapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding tasks: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // no-op once the rename succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("syncing temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpName, s.path); err != nil {
+		return fmt.Errorf("replacing %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// Close is a no-op; the file is only open while saving
+func (s *JSONStore) Close() error {
+	return nil
+}
@@ -1,10 +1,354 @@
 package tasks
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"task-manager/utils"
+	"time"
 )
 
+var (
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrAlreadyCompleted = errors.New("task is already completed")
+	ErrInvalidPriority  = errors.New("invalid priority, choose High, Medium, or Low")
+	ErrInvalidStatus    = errors.New("invalid status, choose Pending, Blocked, or Completed")
+	ErrEmptyDescription = errors.New("task description cannot be empty")
+	ErrInvalidSort      = errors.New("invalid sort order, choose id, priority, due, or next")
+	ErrNoChanges        = errors.New("no changes given")
+)
+
+// TaskSpec holds the user-supplied fields of a new task
+type TaskSpec struct {
+	Description string
+	Priority    string
+	Due         *time.Time
+	Tags        []string
+	DependsOn   []int
+	Recurrence  string // optional, see ParseRecurrence
+}
+
+// Filter narrows down ListTasks; empty fields match everything
+type Filter struct {
+	Status   string
+	Priority string
+	Tag      string
+	Overdue  bool   // only unfinished tasks past their due date
+	SortBy   string // "id" (default), "priority", "due" or "next" (dependency order)
+}
+
+// CreateTask validates and stores a new task in Default without prompting
+func CreateTask(spec TaskSpec) (Task, error) {
+	return Default.Add(spec)
+}
+
+func (s *SafeStore) add(spec TaskSpec) (Task, error) {
+	description := strings.TrimSpace(spec.Description)
+	if description == "" {
+		return Task{}, ErrEmptyDescription
+	}
+	priority := NormalizePriority(spec.Priority)
+	if priority == "" {
+		return Task{}, ErrInvalidPriority
+	}
+
+	deps, err := validateDependencies(s.tasks, s.nextID, spec.DependsOn)
+	if err != nil {
+		return Task{}, err
+	}
+	recurrence, err := normalizeRecurrence(spec.Recurrence)
+	if err != nil {
+		return Task{}, err
+	}
+
+	task := Task{
+		ID:          s.nextID,
+		Description: description,
+		Status:      "Pending",
+		Priority:    priority,
+		Due:         spec.Due,
+		Tags:        spec.Tags,
+		CreatedAt:   now(),
+		DependsOn:   deps,
+		Recurrence:  recurrence,
+	}.clone()
+	if len(pendingDependencies(s.tasks, task)) > 0 {
+		task.Status = "Blocked"
+	}
+	cp := s.saveCheckpoint()
+	s.tasks = append(s.tasks, task)
+	s.nextID++
+	s.record(Change{Op: "add", Summary: fmt.Sprintf("add task %d: %s", task.ID, task.Description), Diffs: []Diff{added(task)}})
+	if err := s.commit(cp); err != nil {
+		return Task{}, err
+	}
+	return task, nil
+}
+
+// CompleteTask marks the task with the given ID as completed without prompting.
+// For a repeating task the next occurrence is added as a new task and returned.
+func CompleteTask(id int) (*Task, error) {
+	_, next, err := Default.Complete(id)
+	return next, err
+}
+
+func (s *SafeStore) complete(id int) (*Task, error) {
+	i := indexIn(s.tasks, id)
+	if i < 0 {
+		return nil, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+	}
+	if s.tasks[i].Status == "Completed" {
+		return nil, ErrAlreadyCompleted
+	}
+	if pending := pendingDependencies(s.tasks, s.tasks[i]); len(pending) > 0 {
+		return nil, fmt.Errorf("task %d waits on %s: %w", id, formatIDs(pending), ErrTaskBlocked)
+	}
+
+	completedAt := now()
+	var next *Task
+	if s.tasks[i].Recurrence != "" {
+		due, err := nextOccurrence(s.tasks[i], completedAt)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", id, err)
+		}
+		spawned := Task{
+			ID:          s.nextID,
+			Description: s.tasks[i].Description,
+			Status:      "Pending",
+			Priority:    s.tasks[i].Priority,
+			Due:         &due,
+			Tags:        s.tasks[i].Tags,
+			CreatedAt:   completedAt,
+			Recurrence:  s.tasks[i].Recurrence,
+		}.clone()
+		next = &spawned
+	}
+
+	cp := s.saveCheckpoint()
+	before := s.tasks[i].clone()
+	s.tasks[i].Status = "Completed"
+	s.tasks[i].CompletedAt = &completedAt
+	diffs := []Diff{changed(before, s.tasks[i])}
+	summary := fmt.Sprintf("complete task %d", id)
+	if next != nil {
+		s.tasks = append(s.tasks, *next)
+		s.nextID++
+		diffs = append(diffs, added(*next))
+		summary += fmt.Sprintf(", next occurrence is task %d due %s", next.ID, FormatDueDate(next.Due))
+	}
+	refreshBlocked(s.tasks)
+	s.record(Change{Op: "complete", Summary: summary, Diffs: diffs})
+	if err := s.commit(cp); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// normalizeRecurrence validates expr and returns its canonical form
+func normalizeRecurrence(expr string) (string, error) {
+	if strings.TrimSpace(expr) == "" {
+		return "", nil
+	}
+	rule, err := ParseRecurrence(expr)
+	if err != nil {
+		return "", err
+	}
+	return rule.String(), nil
+}
+
+// TaskEdit lists the fields to change; nil fields are left as they are
+type TaskEdit struct {
+	Description *string
+	Priority    *string
+	Due         *time.Time
+	ClearDue    bool
+	Tags        *[]string
+	DependsOn   *[]int  // replaces the whole list; an empty list removes all
+	Recurrence  *string // "" stops the task from repeating
+}
+
+// EditTask changes the given fields of a task and returns the result
+func EditTask(id int, edit TaskEdit) (Task, error) {
+	return Default.Edit(id, edit)
+}
+
+func (s *SafeStore) edit(id int, edit TaskEdit) (Task, error) {
+	i := indexIn(s.tasks, id)
+	if i < 0 {
+		return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+	}
+
+	before := s.tasks[i].clone()
+	after := s.tasks[i].clone()
+	var fields []string
+	if edit.Description != nil {
+		description := strings.TrimSpace(*edit.Description)
+		if description == "" {
+			return Task{}, ErrEmptyDescription
+		}
+		after.Description = description
+		fields = append(fields, "description")
+	}
+	if edit.Priority != nil {
+		priority := NormalizePriority(*edit.Priority)
+		if priority == "" {
+			return Task{}, ErrInvalidPriority
+		}
+		after.Priority = priority
+		fields = append(fields, "priority")
+	}
+	if edit.ClearDue {
+		after.Due = nil
+		fields = append(fields, "due")
+	} else if edit.Due != nil {
+		due := *edit.Due
+		after.Due = &due
+		fields = append(fields, "due")
+	}
+	if edit.Tags != nil {
+		after.Tags = append([]string(nil), (*edit.Tags)...)
+		fields = append(fields, "tags")
+	}
+	if edit.DependsOn != nil {
+		deps, err := validateDependencies(s.tasks, id, *edit.DependsOn)
+		if err != nil {
+			return Task{}, err
+		}
+		after.DependsOn = deps
+		fields = append(fields, "dependencies")
+	}
+	if edit.Recurrence != nil {
+		recurrence, err := normalizeRecurrence(*edit.Recurrence)
+		if err != nil {
+			return Task{}, err
+		}
+		after.Recurrence = recurrence
+		fields = append(fields, "recurrence")
+	}
+	if len(fields) == 0 {
+		return before, ErrNoChanges
+	}
+
+	cp := s.saveCheckpoint()
+	s.tasks[i] = after
+	refreshBlocked(s.tasks)
+	after = s.tasks[i].clone()
+	s.record(Change{
+		Op:      "edit",
+		Summary: fmt.Sprintf("edit task %d: %s", id, strings.Join(fields, ", ")),
+		Diffs:   []Diff{changed(before, after)},
+	})
+	if err := s.commit(cp); err != nil {
+		return Task{}, err
+	}
+	return after, nil
+}
+
+// DeleteTask removes a task; its ID is never handed out again
+func DeleteTask(id int) error {
+	return Default.Delete(id)
+}
+
+func (s *SafeStore) delete(id int) error {
+	i := indexIn(s.tasks, id)
+	if i < 0 {
+		return fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+	}
+
+	// Tasks that depended on it lose the link; the diffs let undo put it back.
+	cp := s.saveCheckpoint()
+	task := s.tasks[i]
+	s.tasks = append(s.tasks[:i:i], s.tasks[i+1:]...)
+	diffs := append(removeDependency(s.tasks, id), removed(task))
+	refreshBlocked(s.tasks)
+	s.record(Change{Op: "delete", Summary: fmt.Sprintf("delete task %d: %s", id, task.Description), Diffs: diffs})
+	return s.commit(cp)
+}
+
+// ListTasks returns a copy of the tasks that match the filter, in the requested order
+func ListTasks(filter Filter) ([]Task, error) {
+	return Default.List(filter)
+}
+
+func (s *SafeStore) list(filter Filter) ([]Task, error) {
+	status, priority := "", ""
+	if filter.Status != "" {
+		if status = NormalizeStatus(filter.Status); status == "" {
+			return nil, ErrInvalidStatus
+		}
+	}
+	if filter.Priority != "" {
+		if priority = NormalizePriority(filter.Priority); priority == "" {
+			return nil, ErrInvalidPriority
+		}
+	}
+
+	at := now()
+	matched := make([]Task, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		if status != "" && task.Status != status {
+			continue
+		}
+		if priority != "" && task.Priority != priority {
+			continue
+		}
+		if filter.Tag != "" && !task.HasTag(filter.Tag) {
+			continue
+		}
+		if filter.Overdue && !task.IsOverdue(at) {
+			continue
+		}
+		matched = append(matched, task.clone())
+	}
+
+	if err := SortTasks(matched, filter.SortBy); err != nil {
+		return nil, err
+	}
+	return matched, nil
+}
+
+// SortTasks orders list in place by "id", "priority" or "due".
+// Ties fall back to priority and then ID; tasks without a due date sort last.
+func SortTasks(list []Task, by string) error {
+	var less func(a, b Task) bool
+	switch strings.ToLower(by) {
+	case "", "id":
+		less = func(a, b Task) bool { return a.ID < b.ID }
+	case "priority":
+		less = func(a, b Task) bool {
+			if PriorityRank(a.Priority) != PriorityRank(b.Priority) {
+				return PriorityRank(a.Priority) < PriorityRank(b.Priority)
+			}
+			return a.ID < b.ID
+		}
+	case "next":
+		copy(list, topoOrder(list))
+		return nil
+	case "due":
+		less = func(a, b Task) bool {
+			switch {
+			case a.Due == nil && b.Due == nil:
+			case a.Due == nil:
+				return false
+			case b.Due == nil:
+				return true
+			case !a.Due.Equal(*b.Due):
+				return a.Due.Before(*b.Due)
+			}
+			if PriorityRank(a.Priority) != PriorityRank(b.Priority) {
+				return PriorityRank(a.Priority) < PriorityRank(b.Priority)
+			}
+			return a.ID < b.ID
+		}
+	default:
+		return ErrInvalidSort
+	}
+
+	sort.SliceStable(list, func(i, j int) bool { return less(list[i], list[j]) })
+	return nil
+}
+
 // AddTask adds a new task to the list
 func AddTask() {
 	description := utils.GetStringInput("Enter task description: ")
@@ -19,51 +363,260 @@ func AddTask() {
 		fmt.Println("Invalid priority. Please choose High, Medium, or Low.")
 	}
 
-	task := Task{
-		ID:          nextID,
-		Description: description,
-		Status:      "Pending",
-		Priority:    priority,
+	var due *time.Time
+	for {
+		var err error
+		due, err = ParseDueDate(utils.GetStringInput("Enter due date (YYYY-MM-DD, blank for none): "))
+		if err == nil {
+			break
+		}
+		fmt.Println("Invalid due date. Please use YYYY-MM-DD.")
+	}
+
+	tags := ParseTags(utils.GetStringInput("Enter tags (comma separated, blank for none): "))
+
+	var recurrence string
+	for {
+		recurrence = utils.GetStringInput("Repeat (daily, weekly:mon,thu, every 3 days, cron:0 9 * * 1; blank for never): ")
+		if _, err := normalizeRecurrence(recurrence); err == nil {
+			break
+		}
+		fmt.Println("Invalid repeat rule. Please try again.")
+	}
+
+	spec := TaskSpec{Description: description, Priority: priority, Due: due, Tags: tags, Recurrence: recurrence}
+	if _, err := CreateTask(spec); err != nil {
+		fmt.Println("Error:", err)
+		return
 	}
-	tasks = append(tasks, task)
-	nextID++
 	fmt.Println("Task added successfully.")
 }
 
 // ViewTasks displays the current list of tasks
 func ViewTasks() {
-	if len(tasks) == 0 {
-		fmt.Println("No tasks available.")
+	list, err := ListTasks(Filter{})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printTasks("\nCurrent Tasks:", "No tasks available.", list)
+}
+
+// ViewTasksSorted displays every task ordered by "priority" or "due"
+func ViewTasksSorted(by string) {
+	list, err := ListTasks(Filter{SortBy: by})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printTasks("\nTasks by "+by+":", "No tasks available.", list)
+}
+
+// ViewOverdueTasks displays unfinished tasks past their due date, earliest first
+func ViewOverdueTasks() {
+	list, err := ListTasks(Filter{Overdue: true, SortBy: "due"})
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	printTasks("\nOverdue Tasks:", "No overdue tasks.", list)
+}
+
+func printTasks(title, empty string, list []Task) {
+	if len(list) == 0 {
+		fmt.Println(empty)
 		return
 	}
 
-	fmt.Println("\nCurrent Tasks:")
-	for _, task := range tasks {
-		fmt.Printf("ID: %d | Description: %s | Status: %s | Priority: %s\n",
-			task.ID, task.Description, task.Status, task.Priority)
+	fmt.Println(title)
+	at := now()
+	for _, task := range list {
+		fmt.Println(formatTaskAt(task, at))
 	}
 }
 
+// FormatTask renders a task as a single human-readable line
+func FormatTask(task Task) string {
+	return formatTaskAt(task, now())
+}
+
+func formatTaskAt(task Task, at time.Time) string {
+	line := fmt.Sprintf("ID: %d | Description: %s | Status: %s | Priority: %s",
+		task.ID, task.Description, task.Status, task.Priority)
+	if task.Due != nil {
+		line += " | Due: " + FormatDueDate(task.Due)
+		if task.IsOverdue(at) {
+			line += " (overdue)"
+		}
+	}
+	if len(task.Tags) > 0 {
+		line += " | Tags: " + strings.Join(task.Tags, ", ")
+	}
+	if len(task.DependsOn) > 0 {
+		line += " | Depends on: " + strings.ReplaceAll(formatIDs(task.DependsOn), ",", ", ")
+	}
+	if task.Recurrence != "" {
+		line += " | Repeats: " + task.Recurrence
+	}
+	return line
+}
+
 // MarkTaskCompleted marks a task as completed
 func MarkTaskCompleted() {
-	if len(tasks) == 0 {
+	if Default.Len() == 0 {
 		fmt.Println("No tasks available to mark as completed.")
 		return
 	}
 
 	id := utils.GetIntInput("Enter the task ID to mark as completed: ")
 
-	for i, task := range tasks {
-		if task.ID == id {
-			if task.Status == "Completed" {
-				fmt.Println("Task is already completed.")
-				return
-			}
-			tasks[i].Status = "Completed"
-			fmt.Println("Task marked as completed.")
-			return
+	next, err := CompleteTask(id)
+	switch {
+	case err == nil:
+		fmt.Println("Task marked as completed.")
+		if next != nil {
+			fmt.Printf("Next occurrence added as task %d, due %s.\n", next.ID, FormatDueDate(next.Due))
 		}
+	case errors.Is(err, ErrAlreadyCompleted):
+		fmt.Println("Task is already completed.")
+	case errors.Is(err, ErrTaskNotFound):
+		fmt.Println("Task ID not found.")
+	default:
+		fmt.Println("Error:", err)
 	}
-	fmt.Println("Task ID not found.")
 }
 
+// DependencyPrompt asks for a task ID and the IDs it should depend on
+func DependencyPrompt() {
+	if Default.Len() == 0 {
+		fmt.Println("No tasks available.")
+		return
+	}
+
+	id := utils.GetIntInput("Enter the task ID: ")
+	deps, err := ParseIDs(utils.GetStringInput("Depends on task IDs (comma separated, blank for none): "))
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	task, err := EditTask(id, TaskEdit{DependsOn: &deps})
+	switch {
+	case err == nil:
+		fmt.Println(FormatTask(task))
+	case errors.Is(err, ErrTaskNotFound):
+		fmt.Println("Task ID not found.")
+	default:
+		fmt.Println("Error:", err)
+	}
+}
+
+// EditTaskPrompt asks for a task ID and new values; blank answers keep the old value
+func EditTaskPrompt() {
+	if Default.Len() == 0 {
+		fmt.Println("No tasks available to edit.")
+		return
+	}
+
+	id := utils.GetIntInput("Enter the task ID to edit: ")
+	current, err := Default.Get(id)
+	if err != nil {
+		fmt.Println("Task ID not found.")
+		return
+	}
+	fmt.Println(FormatTask(current))
+
+	var edit TaskEdit
+	if description := utils.GetStringInput("New description (blank to keep): "); description != "" {
+		edit.Description = &description
+	}
+	for {
+		priority := utils.GetStringInput("New priority (High, Medium, Low; blank to keep): ")
+		if priority == "" {
+			break
+		}
+		if NormalizePriority(priority) != "" {
+			edit.Priority = &priority
+			break
+		}
+		fmt.Println("Invalid priority. Please choose High, Medium, or Low.")
+	}
+	for {
+		answer := utils.GetStringInput("New due date (YYYY-MM-DD, \"none\" to clear, blank to keep): ")
+		if answer == "" {
+			break
+		}
+		if strings.EqualFold(answer, "none") {
+			edit.ClearDue = true
+			break
+		}
+		due, err := ParseDueDate(answer)
+		if err == nil {
+			edit.Due = due
+			break
+		}
+		fmt.Println("Invalid due date. Please use YYYY-MM-DD.")
+	}
+	if answer := utils.GetStringInput("New tags (comma separated, \"none\" to clear, blank to keep): "); answer != "" {
+		tags := ParseTags(answer)
+		if strings.EqualFold(answer, "none") {
+			tags = nil
+		}
+		edit.Tags = &tags
+	}
+
+	_, err = EditTask(id, edit)
+	switch {
+	case err == nil:
+		fmt.Println("Task updated.")
+	case errors.Is(err, ErrNoChanges):
+		fmt.Println("Nothing changed.")
+	default:
+		fmt.Println("Error:", err)
+	}
+}
+
+// DeleteTaskPrompt asks for a task ID and removes that task
+func DeleteTaskPrompt() {
+	if Default.Len() == 0 {
+		fmt.Println("No tasks available to delete.")
+		return
+	}
+
+	id := utils.GetIntInput("Enter the task ID to delete: ")
+	err := DeleteTask(id)
+	switch {
+	case err == nil:
+		fmt.Println("Task deleted.")
+	case errors.Is(err, ErrTaskNotFound):
+		fmt.Println("Task ID not found.")
+	default:
+		fmt.Println("Error:", err)
+	}
+}
+
+// UndoPrompt asks how many changes to revert and undoes them
+func UndoPrompt() {
+	n := utils.GetIntInput("How many changes to undo? ")
+	reverts, err := Undo(n)
+	for _, change := range reverts {
+		fmt.Println(change.Summary)
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
+
+// PreviewPrompt asks for a repeating task and shows its next due dates
+func PreviewPrompt() {
+	id := utils.GetIntInput("Enter the task ID to preview: ")
+	n := utils.GetIntInput("How many occurrences? ")
+
+	occurrences, err := PreviewTask(id, n)
+	for i, due := range occurrences {
+		fmt.Printf("%d. %s (%s)\n", i+1, FormatDueDate(&due), due.Weekday())
+	}
+	if err != nil {
+		fmt.Println("Error:", err)
+	}
+}
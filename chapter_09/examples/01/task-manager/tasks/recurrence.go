@@ -0,0 +1,311 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+// Recurrence computes when a repeating task is due next.
+//
+// Supported expressions:
+//
+//	daily
+//	weekly                 same weekday as the previous occurrence
+//	weekly:mon,wed,fri     on the given weekdays
+//	every 3 days           (also "every 3d")
+//	cron:0 9 * * 1-5       minute hour day-of-month month day-of-week
+type Recurrence interface {
+	// Next returns the first occurrence strictly after t
+	Next(t time.Time) time.Time
+	// String returns the canonical expression, as ParseRecurrence reads it
+	String() string
+}
+
+// ParseRecurrence reads a recurrence expression
+func ParseRecurrence(expr string) (Recurrence, error) {
+	expr = strings.TrimSpace(expr)
+	lower := strings.ToLower(expr)
+
+	switch {
+	case lower == "daily":
+		return everyDays(1), nil
+	case lower == "weekly":
+		return weekly{}, nil
+	case strings.HasPrefix(lower, "weekly:"):
+		return parseWeekly(lower[len("weekly:"):])
+	case strings.HasPrefix(lower, "every "):
+		return parseEveryDays(strings.TrimSpace(lower[len("every "):]))
+	case strings.HasPrefix(lower, "cron:"):
+		return parseCron(strings.TrimSpace(expr[len("cron:"):]))
+	default:
+		return nil, fmt.Errorf("%w %q: use daily, weekly, weekly:mon,thu, every N days or cron:M H DOM MON DOW", ErrInvalidRecurrence, expr)
+	}
+}
+
+// everyDays repeats a fixed number of days after the previous occurrence
+type everyDays int
+
+func (d everyDays) Next(t time.Time) time.Time {
+	return t.AddDate(0, 0, int(d))
+}
+
+func (d everyDays) String() string {
+	if d == 1 {
+		return "daily"
+	}
+	return fmt.Sprintf("every %d days", int(d))
+}
+
+func parseEveryDays(spec string) (Recurrence, error) {
+	count := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(spec, "days"), "day"), "d")
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("%w %q: expected every N days", ErrInvalidRecurrence, "every "+spec)
+	}
+	return everyDays(n), nil
+}
+
+// weekly repeats on a set of weekdays; an empty set means the weekday of the previous occurrence
+type weekly struct {
+	days [7]bool
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func parseWeekly(spec string) (Recurrence, error) {
+	var w weekly
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for day, short := range weekdayNames {
+			if len(name) >= 3 && strings.HasPrefix(name, short) {
+				w.days[day] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unknown weekday %q", ErrInvalidRecurrence, name)
+		}
+	}
+	return w, nil
+}
+
+func (w weekly) Next(t time.Time) time.Time {
+	if w.days == [7]bool{} {
+		return t.AddDate(0, 0, 7)
+	}
+	for i := 1; i <= 7; i++ {
+		next := t.AddDate(0, 0, i)
+		if w.days[next.Weekday()] {
+			return next
+		}
+	}
+	return t.AddDate(0, 0, 7) // unreachable: at least one day is set
+}
+
+func (w weekly) String() string {
+	var days []string
+	for day, set := range w.days {
+		if set {
+			days = append(days, weekdayNames[day])
+		}
+	}
+	if len(days) == 0 {
+		return "weekly"
+	}
+	return "weekly:" + strings.Join(days, ",")
+}
+
+// cron is a five-field cron expression evaluated in local time
+type cron struct {
+	expr                          string
+	minute, hour, dom, month, dow []bool
+	domRestricted, dowRestricted  bool
+}
+
+// cronSearchLimit bounds the search for expressions that can never match, like Feb 30
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+func parseCron(expr string) (Recurrence, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: cron needs 5 fields (minute hour day-of-month month day-of-week), got %d", ErrInvalidRecurrence, len(fields))
+	}
+
+	c := cron{expr: strings.Join(fields, " ")}
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("%w: minute: %v", ErrInvalidRecurrence, err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("%w: hour: %v", ErrInvalidRecurrence, err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("%w: day of month: %v", ErrInvalidRecurrence, err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("%w: month: %v", ErrInvalidRecurrence, err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("%w: day of week: %v", ErrInvalidRecurrence, err)
+	}
+	c.dow[0] = c.dow[0] || c.dow[7] // 7 is Sunday too
+	c.domRestricted = fields[2] != "*"
+	c.dowRestricted = fields[4] != "*"
+	return c, nil
+}
+
+// parseCronField reads "*", "5", "1-5", "*/15", "1-30/2" and comma separated lists of those
+func parseCronField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(bounds[0])
+			hi, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", rangePart)
+			}
+			lo, hi = value, value
+			if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is outside %d-%d", rangePart, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+func (c cron) dayMatches(t time.Time) bool {
+	domOK, dowOK := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	// Standard cron: when both day fields are restricted, either one may match.
+	if c.domRestricted && c.dowRestricted {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
+
+func (c cron) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+
+	for next.Before(limit) {
+		switch {
+		case !c.month[int(next.Month())]:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !c.dayMatches(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case !c.hour[next.Hour()]:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case !c.minute[next.Minute()]:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{} // never matches
+}
+
+func (c cron) String() string {
+	return "cron:" + c.expr
+}
+
+// PreviewRecurrence lists the next n occurrences of expr after from; n must be at least 1
+func PreviewRecurrence(expr string, from time.Time, n int) ([]time.Time, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("invalid occurrence count %d", n)
+	}
+	rule, err := ParseRecurrence(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]time.Time, 0, n)
+	for t := from; len(occurrences) < n; {
+		t = rule.Next(t)
+		if t.IsZero() {
+			return occurrences, fmt.Errorf("%w: %s never matches", ErrInvalidRecurrence, rule)
+		}
+		occurrences = append(occurrences, t)
+	}
+	return occurrences, nil
+}
+
+// PreviewTask lists the next n due dates of a repeating task, starting from
+// its current due date (or today when it has none).
+func PreviewTask(id int, n int) ([]time.Time, error) {
+	task, err := Default.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if task.Recurrence == "" {
+		return nil, fmt.Errorf("task %d does not repeat", id)
+	}
+
+	from := today()
+	if task.Due != nil {
+		from = *task.Due
+	}
+	return PreviewRecurrence(task.Recurrence, from, n)
+}
+
+// nextOccurrence returns the due date of the task that follows task once it
+// is completed at completedAt. Occurrences that would already be overdue at
+// that moment are skipped, so finishing late does not create a backlog.
+func nextOccurrence(task Task, completedAt time.Time) (time.Time, error) {
+	rule, err := ParseRecurrence(task.Recurrence)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	due := today()
+	if task.Due != nil {
+		due = *task.Due
+	}
+	for {
+		due = rule.Next(due)
+		if due.IsZero() {
+			return due, fmt.Errorf("%w: %s never matches", ErrInvalidRecurrence, rule)
+		}
+		candidate := Task{Due: &due}
+		if !candidate.IsOverdue(completedAt) {
+			return due, nil
+		}
+	}
+}
+
+// today is midnight at the start of the current local day
+func today() time.Time {
+	t := now()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,112 @@
+package tasks
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SafeStore is a task list together with its change log and the Store it is
+// saved to. Every exported method takes the lock, so one SafeStore can be
+// shared between goroutines, e.g. HTTP handlers. The package functions
+// (CreateTask, ListTasks, the menu prompts, ...) all work on Default.
+type SafeStore struct {
+	mu      sync.RWMutex
+	tasks   []Task
+	nextID  int
+	history []Change
+	store   Store // nil keeps changes in memory only
+}
+
+// NewSafeStore returns an empty task list that is kept in memory until Use is called
+func NewSafeStore() *SafeStore {
+	return &SafeStore{tasks: make([]Task, 0), nextID: 1, history: make([]Change, 0)}
+}
+
+// Default is the task list behind the package functions, the menu and the CLI
+var Default = NewSafeStore()
+
+// Use loads the saved tasks from store and persists every later change to it
+func (s *SafeStore) Use(store Store) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.use(store)
+}
+
+// Close releases the current store; later changes stay in memory only
+func (s *SafeStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.close()
+}
+
+// Add creates a task, see CreateTask
+func (s *SafeStore) Add(spec TaskSpec) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(spec)
+}
+
+// List returns the tasks matching filter, see ListTasks
+func (s *SafeStore) List(filter Filter) ([]Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list(filter)
+}
+
+// Len returns the number of tasks, completed ones included
+func (s *SafeStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tasks)
+}
+
+// Get returns a copy of one task
+func (s *SafeStore) Get(id int) (Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := indexIn(s.tasks, id)
+	if i < 0 {
+		return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+	}
+	return s.tasks[i].clone(), nil
+}
+
+// Complete marks a task as completed and returns it together with the
+// next occurrence of a repeating task (nil otherwise), see CompleteTask
+func (s *SafeStore) Complete(id int) (Task, *Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next, err := s.complete(id)
+	if err != nil {
+		return Task{}, nil, err
+	}
+	return s.tasks[indexIn(s.tasks, id)].clone(), next, nil
+}
+
+// Edit changes the given fields of a task, see EditTask
+func (s *SafeStore) Edit(id int, edit TaskEdit) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.edit(id, edit)
+}
+
+// Delete removes a task, see DeleteTask
+func (s *SafeStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(id)
+}
+
+// Undo reverts the last n changes, see Undo
+func (s *SafeStore) Undo(n int) ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.undo(n)
+}
+
+// History returns a copy of the change log, oldest first
+func (s *SafeStore) History() []Change {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Change(nil), s.history...)
+}
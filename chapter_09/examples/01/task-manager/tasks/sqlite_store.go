@@ -0,0 +1,245 @@
+package tasks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore keeps the task list in a SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// addedColumns are task columns introduced after the first release; they are
+// added to older databases on open so existing rows keep loading.
+var addedColumns = []struct{ name, definition string }{
+	{"due", "TEXT"},
+	{"tags", "TEXT NOT NULL DEFAULT ''"},
+	{"created_at", "TEXT"},
+	{"completed_at", "TEXT"},
+	{"depends_on", "TEXT NOT NULL DEFAULT ''"},
+	{"recurrence", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// NewSQLiteStore opens (or creates) the database at path and its tables
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	createTables := `
+	CREATE TABLE IF NOT EXISTS tasks (
+		id INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		status TEXT NOT NULL,
+		priority TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value INTEGER NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS history (
+		seq INTEGER PRIMARY KEY,
+		change TEXT NOT NULL
+	)`
+
+	if _, err := db.Exec(createTables); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating tables: %w", err)
+	}
+	if err := addMissingColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("upgrading tasks table: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func addMissingColumns(db *sql.DB) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info('tasks')")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range addedColumns {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE tasks ADD COLUMN " + column.name + " " + column.definition); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load reads every task and the saved ID counter
+func (s *SQLiteStore) Load() (Snapshot, error) {
+	var snapshot Snapshot
+
+	err := s.db.QueryRow("SELECT value FROM meta WHERE key = 'next_id'").Scan(&snapshot.NextID)
+	if err != nil && err != sql.ErrNoRows {
+		return snapshot, fmt.Errorf("reading next_id: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+	SELECT id, description, status, priority, due, tags, created_at, completed_at, depends_on, recurrence
+	FROM tasks ORDER BY id`)
+	if err != nil {
+		return snapshot, fmt.Errorf("reading tasks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task Task
+		var due, createdAt, completedAt sql.NullString
+		var tags, dependsOn string
+		if err := rows.Scan(&task.ID, &task.Description, &task.Status, &task.Priority,
+			&due, &tags, &createdAt, &completedAt, &dependsOn, &task.Recurrence); err != nil {
+			return snapshot, fmt.Errorf("reading tasks: %w", err)
+		}
+		if task.DependsOn, err = ParseIDs(dependsOn); err != nil {
+			return snapshot, fmt.Errorf("task %d depends_on: %w", task.ID, err)
+		}
+
+		task.Tags = ParseTags(tags)
+		if task.Due, err = parseNullTime(due); err != nil {
+			return snapshot, fmt.Errorf("task %d due: %w", task.ID, err)
+		}
+		if task.CompletedAt, err = parseNullTime(completedAt); err != nil {
+			return snapshot, fmt.Errorf("task %d completed_at: %w", task.ID, err)
+		}
+		created, err := parseNullTime(createdAt)
+		if err != nil {
+			return snapshot, fmt.Errorf("task %d created_at: %w", task.ID, err)
+		}
+		if created != nil {
+			task.CreatedAt = *created
+		}
+
+		snapshot.Tasks = append(snapshot.Tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return snapshot, err
+	}
+
+	snapshot.History, err = s.loadHistory()
+	return snapshot, err
+}
+
+func (s *SQLiteStore) loadHistory() ([]Change, error) {
+	rows, err := s.db.Query("SELECT change FROM history ORDER BY seq")
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("reading history: %w", err)
+		}
+		var change Change
+		if err := json.Unmarshal([]byte(data), &change); err != nil {
+			return nil, fmt.Errorf("parsing history: %w", err)
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// Save replaces the stored tasks inside one transaction
+func (s *SQLiteStore) Save(snapshot Snapshot) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // no-op after a successful commit
+
+	if _, err := tx.Exec("DELETE FROM tasks"); err != nil {
+		return err
+	}
+
+	insert, err := tx.Prepare(`
+	INSERT INTO tasks (id, description, status, priority, due, tags, created_at, completed_at, depends_on, recurrence)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, task := range snapshot.Tasks {
+		_, err := insert.Exec(task.ID, task.Description, task.Status, task.Priority,
+			nullTime(task.Due), strings.Join(task.Tags, ","), nullTime(&task.CreatedAt), nullTime(task.CompletedAt), formatIDs(task.DependsOn), task.Recurrence)
+		if err != nil {
+			return fmt.Errorf("saving task %d: %w", task.ID, err)
+		}
+	}
+
+	if _, err := tx.Exec("INSERT OR REPLACE INTO meta (key, value) VALUES ('next_id', ?)", snapshot.NextID); err != nil {
+		return err
+	}
+
+	// The change log is append-only: only entries newer than the last stored one are written.
+	var lastSeq int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM history").Scan(&lastSeq); err != nil {
+		return err
+	}
+	for _, change := range snapshot.History {
+		if change.Seq <= lastSeq {
+			continue
+		}
+		data, err := json.Marshal(change)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO history (seq, change) VALUES (?, ?)", change.Seq, string(data)); err != nil {
+			return fmt.Errorf("saving change %d: %w", change.Seq, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Close releases the database handle
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// nullTime stores t as RFC 3339 text, or NULL when unset
+func nullTime(t *time.Time) sql.NullString {
+	if t == nil || t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.Format(time.RFC3339Nano), Valid: true}
+}
+
+func parseNullTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid || value.String == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,119 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Snapshot is everything a Store needs to persist between runs
+type Snapshot struct {
+	NextID  int      `json:"next_id"`
+	Tasks   []Task   `json:"tasks"`
+	History []Change `json:"history,omitempty"`
+}
+
+// Store persists the task list so it survives a restart
+type Store interface {
+	Load() (Snapshot, error)
+	Save(snapshot Snapshot) error
+	Close() error
+}
+
+// OpenStore picks a Store implementation from the file extension of path:
+// ".db", ".sqlite" and ".sqlite3" use SQLite, anything else is a JSON file.
+func OpenStore(path string) (Store, error) {
+	switch {
+	case strings.HasSuffix(path, ".db"),
+		strings.HasSuffix(path, ".sqlite"),
+		strings.HasSuffix(path, ".sqlite3"):
+		return NewSQLiteStore(path)
+	default:
+		return NewJSONStore(path), nil
+	}
+}
+
+// UseStore loads the saved tasks from s into Default and persists every
+// later change to it
+func UseStore(s Store) error {
+	return Default.Use(s)
+}
+
+// CloseStore releases the store of Default; later changes stay in memory only
+func CloseStore() error {
+	return Default.Close()
+}
+
+// use replaces the state of s with the snapshot saved in store
+func (s *SafeStore) use(store Store) error {
+	snapshot, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("loading tasks: %w", err)
+	}
+
+	s.tasks = snapshot.Tasks
+	if s.tasks == nil {
+		s.tasks = make([]Task, 0)
+	}
+	refreshBlocked(s.tasks)
+	s.history = snapshot.History
+	if s.history == nil {
+		s.history = make([]Change, 0)
+	}
+
+	// Never hand out an ID that is already taken, even if the stored
+	// counter is missing or behind.
+	s.nextID = snapshot.NextID
+	for _, task := range s.tasks {
+		if task.ID >= s.nextID {
+			s.nextID = task.ID + 1
+		}
+	}
+	if s.nextID < 1 {
+		s.nextID = 1
+	}
+
+	s.store = store
+	return nil
+}
+
+// close releases the current store, if any
+func (s *SafeStore) close() error {
+	if s.store == nil {
+		return nil
+	}
+	err := s.store.Close()
+	s.store = nil
+	return err
+}
+
+// checkpoint is the in-memory state before a change. commit puts it back
+// when the change can't be saved, so memory never gets ahead of the store.
+type checkpoint struct {
+	tasks   []Task
+	nextID  int
+	history []Change
+}
+
+// saveCheckpoint copies the current state; tasks are cloned because changes
+// edit them in place
+func (s *SafeStore) saveCheckpoint() checkpoint {
+	saved := checkpoint{tasks: make([]Task, len(s.tasks)), nextID: s.nextID, history: s.history[:len(s.history):len(s.history)]}
+	for i, task := range s.tasks {
+		saved.tasks[i] = task.clone()
+	}
+	return saved
+}
+
+// commit writes the in-memory state to the current store, if any. If the
+// save fails, the state goes back to cp and the change is dropped.
+func (s *SafeStore) commit(cp checkpoint) error {
+	if s.store == nil {
+		return nil
+	}
+	snapshot := Snapshot{NextID: s.nextID, Tasks: s.tasks, History: s.history}
+	if err := s.store.Save(snapshot); err != nil {
+		s.tasks, s.nextID, s.history = cp.tasks, cp.nextID, cp.history
+		return fmt.Errorf("saving tasks: %w", err)
+	}
+	return nil
+}
@@ -1,17 +1,30 @@
 package tasks
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Task represents a single task
 type Task struct {
-	ID          int
-	Description string
-	Status      string // "Pending" or "Completed"
-	Priority    string // "High", "Medium", "Low"
+	ID          int        `json:"id"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`   // "Pending", "Blocked" or "Completed"
+	Priority    string     `json:"priority"` // "High", "Medium", "Low"
+	Due         *time.Time `json:"due,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DependsOn   []int      `json:"depends_on,omitempty"` // IDs that must be completed first
+	Recurrence  string     `json:"recurrence,omitempty"` // see ParseRecurrence
 }
 
-var tasks = make([]Task, 0)
-var nextID = 1
+// now is swapped out when a fixed clock is needed
+var now = time.Now
+
+// dateLayout is the format for due dates without a time of day
+const dateLayout = "2006-01-02"
 
 // NormalizePriority ensures priority is valid
 func NormalizePriority(priority string) string {
@@ -21,3 +34,115 @@ func NormalizePriority(priority string) string {
 	}
 	return priority
 }
+
+// PriorityRank orders priorities High < Medium < Low; unknown values sort last
+func PriorityRank(priority string) int {
+	switch NormalizePriority(priority) {
+	case "High":
+		return 0
+	case "Medium":
+		return 1
+	case "Low":
+		return 2
+	default:
+		return 3
+	}
+}
+
+// NormalizeStatus ensures status is valid.
+// "Blocked" is never set by hand; it is derived from DependsOn.
+func NormalizeStatus(status string) string {
+	status = strings.Title(strings.ToLower(status))
+	if status != "Pending" && status != "Blocked" && status != "Completed" {
+		return ""
+	}
+	return status
+}
+
+// ParseDueDate accepts YYYY-MM-DD or RFC 3339; an empty string means no due date
+func ParseDueDate(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if due, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+		return &due, nil
+	}
+	due, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid due date %q, use YYYY-MM-DD", value)
+	}
+	return &due, nil
+}
+
+// FormatDueDate renders a due date the way ParseDueDate reads it back
+func FormatDueDate(due *time.Time) string {
+	if due == nil {
+		return ""
+	}
+	if isDateOnly(*due) {
+		return due.Format(dateLayout)
+	}
+	return due.Format(time.RFC3339)
+}
+
+// ParseTags splits a comma separated list, dropping blanks and duplicates
+func ParseTags(value string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// HasTag reports whether the task carries tag, ignoring case
+func (t Task) HasTag(tag string) bool {
+	for _, existing := range t.Tags {
+		if strings.EqualFold(existing, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOverdue reports whether an unfinished task is past its due date.
+// A date-only due date lasts until the end of that day.
+func (t Task) IsOverdue(at time.Time) bool {
+	if t.Due == nil || t.Status == "Completed" {
+		return false
+	}
+	deadline := *t.Due
+	if isDateOnly(deadline) {
+		deadline = deadline.AddDate(0, 0, 1)
+	}
+	return !at.Before(deadline)
+}
+
+// clone returns a copy that shares no slices or pointers with t
+func (t Task) clone() Task {
+	if t.Due != nil {
+		due := *t.Due
+		t.Due = &due
+	}
+	if t.CompletedAt != nil {
+		completed := *t.CompletedAt
+		t.CompletedAt = &completed
+	}
+	if t.Tags != nil {
+		t.Tags = append([]string(nil), t.Tags...)
+	}
+	if t.DependsOn != nil {
+		t.DependsOn = append([]int(nil), t.DependsOn...)
+	}
+	return t
+}
+
+func isDateOnly(t time.Time) bool {
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"task-manager/api"
+	"task-manager/tasks"
+)
+
+// newServer starts the API on an empty task list kept in a temporary JSON file
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store := tasks.NewSafeStore()
+	if err := store.Use(tasks.NewJSONStore(filepath.Join(t.TempDir(), "tasks.json"))); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(api.NewHandler(store))
+	t.Cleanup(func() {
+		server.Close()
+		store.Close()
+	})
+	return server
+}
+
+// call sends a JSON request and decodes the JSON response into out, if given
+func call(t *testing.T, method, url string, body any, out any) int {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Errorf("%s %s: decoding response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// TestTaskLifecycle adds, lists, edits, completes and deletes a task
+func TestTaskLifecycle(t *testing.T) {
+	server := newServer(t)
+
+	var task tasks.Task
+	if code := call(t, "POST", server.URL+"/tasks", map[string]any{"description": "Write tests", "priority": "high", "tags": []string{"go"}}, &task); code != http.StatusCreated {
+		t.Fatalf("POST /tasks: got %d, want 201", code)
+	}
+	if task.ID != 1 || task.Priority != "High" || task.Status != "Pending" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	var list []tasks.Task
+	if code := call(t, "GET", server.URL+"/tasks?tag=go", nil, &list); code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("GET /tasks: got %d with %d tasks", code, len(list))
+	}
+
+	if code := call(t, "PATCH", server.URL+"/tasks/1", map[string]any{"priority": "low", "due": "2030-01-02"}, &task); code != http.StatusOK {
+		t.Fatalf("PATCH edit: got %d", code)
+	}
+	if task.Priority != "Low" || tasks.FormatDueDate(task.Due) != "2030-01-02" {
+		t.Errorf("edit not applied: %+v", task)
+	}
+
+	if code := call(t, "PATCH", server.URL+"/tasks/1", map[string]any{"status": "completed"}, &task); code != http.StatusOK || task.Status != "Completed" {
+		t.Fatalf("PATCH complete: got %d, status %q", code, task.Status)
+	}
+	if code := call(t, "PATCH", server.URL+"/tasks/1", map[string]any{"status": "completed"}, nil); code != http.StatusConflict {
+		t.Errorf("completing twice: got %d, want 409", code)
+	}
+
+	if code := call(t, "DELETE", server.URL+"/tasks/1", nil, nil); code != http.StatusNoContent {
+		t.Fatalf("DELETE: got %d, want 204", code)
+	}
+	if code := call(t, "GET", server.URL+"/tasks/1", nil, nil); code != http.StatusNotFound {
+		t.Errorf("GET deleted task: got %d, want 404", code)
+	}
+}
+
+// TestInvalidRequests checks that bad input is rejected with 400
+func TestInvalidRequests(t *testing.T) {
+	server := newServer(t)
+
+	cases := []struct {
+		method, path string
+		body         any
+	}{
+		{"POST", "/tasks", map[string]any{"description": "", "priority": "high"}},
+		{"POST", "/tasks", map[string]any{"description": "x", "priority": "urgent"}},
+		{"POST", "/tasks", map[string]any{"description": "x", "priority": "high", "depends_on": []int{42}}},
+		{"POST", "/tasks", map[string]any{"description": "x", "priority": "high", "unknown": true}},
+		{"GET", "/tasks?sort=name", nil},
+		{"GET", "/tasks/abc", nil},
+		{"PATCH", "/tasks/1", map[string]any{"status": "pending"}},
+	}
+	for _, c := range cases {
+		if code := call(t, c.method, server.URL+c.path, c.body, nil); code != http.StatusBadRequest {
+			t.Errorf("%s %s %v: got %d, want 400", c.method, c.path, c.body, code)
+		}
+	}
+}
+
+// TestConcurrentClients runs many clients at once; every add must get its
+// own ID and a task can only be completed by one of them
+func TestConcurrentClients(t *testing.T) {
+	server := newServer(t)
+	const clients = 50
+
+	var wg sync.WaitGroup
+	ids := make(chan int, clients)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var task tasks.Task
+			body := map[string]any{"description": fmt.Sprintf("task %d", i), "priority": "medium"}
+			if code := call(t, "POST", server.URL+"/tasks", body, &task); code != http.StatusCreated {
+				t.Errorf("POST: got %d", code)
+				return
+			}
+			ids <- task.ID
+			call(t, "GET", server.URL+"/tasks", nil, nil)
+		}(i)
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("ID %d handed out twice", id)
+		}
+		seen[id] = true
+	}
+	var list []tasks.Task
+	call(t, "GET", server.URL+"/tasks", nil, &list)
+	if len(list) != clients {
+		t.Fatalf("got %d tasks, want %d", len(list), clients)
+	}
+
+	var mu sync.Mutex
+	codes := make(map[int]int)
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			code := call(t, "PATCH", server.URL+"/tasks/1", map[string]any{"status": "completed"}, nil)
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if codes[http.StatusOK] != 1 || codes[http.StatusConflict] != clients-1 {
+		t.Errorf("completing one task from %d clients: got status counts %v", clients, codes)
+	}
+
+	for _, task := range list {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if code := call(t, "DELETE", fmt.Sprintf("%s/tasks/%d", server.URL, id), nil, nil); code != http.StatusNoContent {
+				t.Errorf("DELETE %d: got %d", id, code)
+			}
+		}(task.ID)
+	}
+	wg.Wait()
+	list = nil
+	call(t, "GET", server.URL+"/tasks", nil, &list)
+	if len(list) != 0 {
+		t.Errorf("%d tasks left after deleting all", len(list))
+	}
+}
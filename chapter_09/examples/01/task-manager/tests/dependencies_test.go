@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"task-manager/tasks"
+)
+
+// statuses renders the task list as "id:status" items
+func statuses(t *testing.T, filter tasks.Filter) string {
+	t.Helper()
+	list, err := tasks.ListTasks(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := make([]string, len(list))
+	for i, task := range list {
+		items[i] = fmt.Sprintf("%d:%s", task.ID, task.Status)
+	}
+	return strings.Join(items, " ")
+}
+
+// TestDependencyValidation rejects self-dependencies, unknown tasks and
+// every edit that would close a loop, and leaves the task unchanged
+func TestDependencyValidation(t *testing.T) {
+	freshTasks(t, "one", "two", "three")
+	chain := []int{1}
+	if _, err := tasks.EditTask(2, tasks.TaskEdit{DependsOn: &chain}); err != nil {
+		t.Fatal(err)
+	}
+	chain = []int{2}
+	if _, err := tasks.EditTask(3, tasks.TaskEdit{DependsOn: &chain}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		id   int
+		deps []int
+		err  error
+		path string // the loop named in the error
+	}{
+		{id: 1, deps: []int{1}, err: tasks.ErrDependencyCycle, path: "itself"},
+		{id: 1, deps: []int{2}, err: tasks.ErrDependencyCycle, path: "1 -> 2 -> 1"},
+		{id: 1, deps: []int{3}, err: tasks.ErrDependencyCycle, path: "1 -> 3 -> 2 -> 1"},
+		{id: 2, deps: []int{3, 1}, err: tasks.ErrDependencyCycle, path: "2 -> 3 -> 2"},
+		{id: 1, deps: []int{9}, err: tasks.ErrUnknownDependency},
+	}
+	for _, c := range cases {
+		deps := c.deps
+		_, err := tasks.EditTask(c.id, tasks.TaskEdit{DependsOn: &deps})
+		if !errors.Is(err, c.err) || !strings.Contains(err.Error(), c.path) {
+			t.Errorf("task %d depends on %v: got %v, want %v (%s)", c.id, c.deps, err, c.err, c.path)
+		}
+	}
+	if _, err := tasks.CreateTask(tasks.TaskSpec{Description: "x", Priority: "low", DependsOn: []int{4}}); !errors.Is(err, tasks.ErrDependencyCycle) {
+		t.Errorf("new task 4 depending on itself: got %v", err)
+	}
+	if got := describe(t); got != "1 one; 2 two <1; 3 three <2" {
+		t.Errorf("rejected edits changed the tasks: %q", got)
+	}
+
+	// Duplicates are dropped and the list is sorted.
+	deps := []int{2, 1, 2}
+	task, err := tasks.EditTask(3, tasks.TaskEdit{DependsOn: &deps})
+	if err != nil || fmt.Sprint(task.DependsOn) != "[1 2]" {
+		t.Errorf("got %v, %v; want [1 2]", task.DependsOn, err)
+	}
+}
+
+// TestBlockedStatus checks that tasks are blocked until everything they
+// depend on is completed, and free up again afterwards
+func TestBlockedStatus(t *testing.T) {
+	freshTasks(t, "design", "build")
+	tasks.CreateTask(tasks.TaskSpec{Description: "ship", Priority: "high", DependsOn: []int{1, 2}})
+	if got := statuses(t, tasks.Filter{}); got != "1:Pending 2:Pending 3:Blocked" {
+		t.Fatalf("got %s", got)
+	}
+	if _, err := tasks.CompleteTask(3); !errors.Is(err, tasks.ErrTaskBlocked) {
+		t.Errorf("completing a blocked task: got %v, want ErrTaskBlocked", err)
+	}
+
+	tasks.CompleteTask(1)
+	if got := statuses(t, tasks.Filter{}); got != "1:Completed 2:Pending 3:Blocked" {
+		t.Errorf("one of two done: got %s", got)
+	}
+	tasks.CompleteTask(2)
+	if got := statuses(t, tasks.Filter{}); got != "1:Completed 2:Completed 3:Pending" {
+		t.Errorf("both done: got %s", got)
+	}
+	if _, err := tasks.CompleteTask(3); err != nil {
+		t.Errorf("completing an unblocked task: %v", err)
+	}
+
+	// Deleting the only unfinished dependency unblocks too.
+	freshTasks(t, "a")
+	tasks.CreateTask(tasks.TaskSpec{Description: "b", Priority: "low", DependsOn: []int{1}})
+	tasks.DeleteTask(1)
+	if got := statuses(t, tasks.Filter{}); got != "2:Pending" {
+		t.Errorf("after deleting the dependency: got %s", got)
+	}
+}
+
+// TestNextOrder lists tasks after the tasks they depend on, higher priority
+// first among those ready, and completed tasks last
+func TestNextOrder(t *testing.T) {
+	freshTasks(t)
+	for _, spec := range []tasks.TaskSpec{
+		{Description: "1", Priority: "low"},
+		{Description: "2", Priority: "high", DependsOn: []int{1}},
+		{Description: "3", Priority: "medium"},
+		{Description: "4", Priority: "high"},
+		{Description: "5", Priority: "high", DependsOn: []int{4}},
+	} {
+		if _, err := tasks.CreateTask(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks.CompleteTask(4)
+	if got := statuses(t, tasks.Filter{SortBy: "next"}); got != "5:Pending 3:Pending 1:Pending 2:Blocked 4:Completed" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"task-manager/tasks"
+)
+
+// changeLog renders the change log as "seq op[>undoes]" items
+func changeLog() string {
+	var items []string
+	for _, change := range tasks.History() {
+		item := fmt.Sprintf("%d %s", change.Seq, change.Op)
+		if change.Undoes != 0 {
+			item += fmt.Sprintf(">%d", change.Undoes)
+		}
+		items = append(items, item)
+	}
+	return fmt.Sprint(items)
+}
+
+// TestUndoRoundTrip edits, deletes and undoes against both stores, then
+// reopens the store and checks that the log and the ID counter survived
+func TestUndoRoundTrip(t *testing.T) {
+	for _, name := range []string{"tasks.json", "tasks.db"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			store, err := tasks.OpenStore(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			useStore(t, store)
+
+			tasks.CreateTask(tasks.TaskSpec{Description: "write", Priority: "low"})
+			tasks.CreateTask(tasks.TaskSpec{Description: "review", Priority: "low", DependsOn: []int{1}})
+
+			desc, priority := "write docs", "high"
+			if _, err := tasks.EditTask(1, tasks.TaskEdit{Description: &desc, Priority: &priority}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tasks.Undo(1); err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(t); got != "1 write; 2 review <1" {
+				t.Errorf("after undoing the edit: %q", got)
+			}
+
+			if err := tasks.DeleteTask(1); err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(t); got != "2 review" {
+				t.Errorf("after delete: %q", got)
+			}
+			if _, err := tasks.Undo(1); err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(t); got != "1 write; 2 review <1" {
+				t.Errorf("after undoing the delete: %q", got)
+			}
+			if blocked, _ := tasks.ListTasks(tasks.Filter{Status: "blocked"}); len(blocked) != 1 || blocked[0].ID != 2 {
+				t.Errorf("task 2 should be blocked again: %+v", blocked)
+			}
+
+			// The edit and the delete are undone already, so this reverts the two adds.
+			reverts, err := tasks.Undo(10)
+			if err != nil || len(reverts) != 2 || reverts[0].Undoes != 2 || reverts[1].Undoes != 1 {
+				t.Fatalf("undo all: got %+v, %v", reverts, err)
+			}
+			if _, err := tasks.Undo(1); !errors.Is(err, tasks.ErrNothingToUndo) {
+				t.Errorf("got %v, want ErrNothingToUndo", err)
+			}
+			want := "[1 add 2 add 3 edit 4 undo>3 5 delete 6 undo>5 7 undo>2 8 undo>1]"
+			if got := changeLog(); got != want {
+				t.Errorf("log: got %s, want %s", got, want)
+			}
+
+			// Reopen: the log is read back in full and freed IDs stay used.
+			if err := tasks.CloseStore(); err != nil {
+				t.Fatal(err)
+			}
+			if store, err = tasks.OpenStore(path); err != nil {
+				t.Fatal(err)
+			}
+			useStore(t, store)
+			if got := changeLog(); got != want {
+				t.Errorf("reopened log: got %s, want %s", got, want)
+			}
+			task, err := tasks.CreateTask(tasks.TaskSpec{Description: "again", Priority: "low"})
+			if err != nil || task.ID != 3 {
+				t.Errorf("got %+v, %v; want task 3", task, err)
+			}
+			if got := changeLog(); got != want[:len(want)-1]+" 9 add]" {
+				t.Errorf("log after reopening: %s", got)
+			}
+		})
+	}
+}
+
+// TestUndoConflict refuses to undo a change that no longer applies and
+// leaves the tasks and the log as they were
+func TestUndoConflict(t *testing.T) {
+	one := tasks.Task{ID: 1, Description: "one", Status: "Pending", Priority: "Low"}
+	gone := tasks.Task{ID: 5, Description: "gone", Status: "Pending", Priority: "Low"}
+	useStore(t, &failingStore{saved: tasks.Snapshot{
+		NextID: 6,
+		Tasks:  []tasks.Task{one},
+		History: []tasks.Change{
+			{Seq: 1, Op: "add", Diffs: []tasks.Diff{{After: &one}}},
+			{Seq: 2, Op: "add", Diffs: []tasks.Diff{{After: &gone}}},
+		},
+	}})
+
+	if _, err := tasks.Undo(2); !errors.Is(err, tasks.ErrTaskNotFound) {
+		t.Fatalf("got %v, want ErrTaskNotFound", err)
+	}
+	if got := describe(t); got != "1 one" {
+		t.Errorf("tasks: got %q", got)
+	}
+	if got := changeLog(); got != "[1 add 2 add]" {
+		t.Errorf("log: got %s", got)
+	}
+}
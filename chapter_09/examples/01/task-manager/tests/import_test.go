@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"task-manager/tasks"
+)
+
+// freshTasks starts an empty task list in a temporary JSON file with the
+// given descriptions added as tasks 1, 2, ...
+func freshTasks(t *testing.T, descriptions ...string) {
+	t.Helper()
+	useStore(t, tasks.NewJSONStore(filepath.Join(t.TempDir(), "tasks.json")))
+	for _, desc := range descriptions {
+		if _, err := tasks.CreateTask(tasks.TaskSpec{Description: desc, Priority: "medium"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// describe renders the task list as "id description [<deps]" items, e.g.
+// "3 child <4; 4 parent"
+func describe(t *testing.T) string {
+	t.Helper()
+	list, err := tasks.ListTasks(tasks.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := make([]string, len(list))
+	for i, task := range list {
+		items[i] = fmt.Sprintf("%d %s", task.ID, task.Description)
+		for _, dep := range task.DependsOn {
+			items[i] += fmt.Sprintf(" <%d", dep)
+		}
+	}
+	return strings.Join(items, "; ")
+}
+
+// TestImport covers both formats, ID remapping with the dependencies that
+// point at remapped IDs, replace mode and rejected files
+func TestImport(t *testing.T) {
+	cases := []struct {
+		name     string
+		json     bool
+		mode     tasks.ImportMode
+		input    string
+		want     string // task list afterwards
+		remapped []tasks.Remap
+		replaced int
+		badLines []int // lines of an ImportError; the list must stay as it was
+		err      error
+	}{
+		{
+			name: "csv merge remaps taken IDs and their dependents",
+			mode: tasks.ImportMerge,
+			input: "ID,Description,Status,Priority,DependsOn\n" +
+				"1,parent,Pending,High,\n" +
+				"3,child,Pending,Low,1\n",
+			want:     "1 one; 2 two; 3 child <4; 4 parent",
+			remapped: []tasks.Remap{{Line: 2, From: 1, To: 4}},
+		},
+		{
+			name: "json merge remaps taken IDs and their dependents",
+			json: true,
+			mode: tasks.ImportMerge,
+			input: `[
+  {"id": 2, "description": "parent", "status": "Pending", "priority": "High"},
+  {"id": 7, "description": "child", "status": "Pending", "priority": "Low", "depends_on": [2, 1]}
+]`,
+			want:     "1 one; 2 two; 7 child <1 <8; 8 parent",
+			remapped: []tasks.Remap{{Line: 2, From: 2, To: 8}},
+		},
+		{
+			name: "rows without an ID get new ones",
+			mode: tasks.ImportMerge,
+			input: "Description,Status,Priority\n" +
+				"new,Pending,Low\n",
+			want: "1 one; 2 two; 3 new",
+		},
+		{
+			name: "replace keeps the file's IDs",
+			mode: tasks.ImportReplace,
+			input: "ID,Description,Status,Priority,DependsOn\n" +
+				"2,second,Completed,High,\n" +
+				"1,first,Pending,Low,2\n",
+			want:     "1 first <2; 2 second",
+			replaced: 2,
+		},
+		{
+			name: "malformed rows are all reported",
+			mode: tasks.ImportMerge,
+			input: "ID,Description,Status,Priority,Due\n" +
+				"5,ok,Pending,High,\n" +
+				"6,bad priority,Pending,Urgent,\n" +
+				"7,,Pending,Low,\n" +
+				"8,bad due,Pending,Low,tomorrow\n" +
+				"x,bad id,Pending,Low,\n",
+			badLines: []int{3, 4, 5, 6},
+		},
+		{
+			name: "unknown dependency",
+			json: true,
+			mode: tasks.ImportMerge,
+			input: `[
+  {"description": "orphan", "status": "Pending", "priority": "Low", "depends_on": [42]}
+]`,
+			badLines: []int{2},
+		},
+		{
+			name: "dependency cycle in the file",
+			mode: tasks.ImportReplace,
+			input: "ID,Description,Status,Priority,DependsOn\n" +
+				"1,a,Pending,Low,2\n" +
+				"2,b,Pending,Low,1\n",
+			badLines: []int{2, 3},
+		},
+		{
+			name:  "missing required column",
+			mode:  tasks.ImportMerge,
+			input: "ID,Description\n1,no status\n",
+			err:   errors.New("missing"),
+		},
+		{
+			name:  "invalid mode",
+			mode:  "append",
+			input: "Description,Status,Priority\nx,Pending,Low\n",
+			err:   tasks.ErrInvalidImportMode,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			freshTasks(t, "one", "two")
+			before := describe(t)
+
+			var result tasks.ImportResult
+			var err error
+			if c.json {
+				result, err = tasks.ImportJSON(strings.NewReader(c.input), c.mode)
+			} else {
+				result, err = tasks.ImportCSV(strings.NewReader(c.input), c.mode)
+			}
+
+			switch {
+			case c.badLines != nil:
+				var importErr *tasks.ImportError
+				if !errors.As(err, &importErr) {
+					t.Fatalf("got %v, want an ImportError", err)
+				}
+				var lines []int
+				for _, row := range importErr.Rows {
+					lines = append(lines, row.Line)
+				}
+				if fmt.Sprint(lines) != fmt.Sprint(c.badLines) {
+					t.Errorf("bad lines %v, want %v", lines, c.badLines)
+				}
+			case c.err != nil:
+				if err == nil || !errors.Is(err, c.err) && !strings.Contains(err.Error(), c.err.Error()) {
+					t.Fatalf("got %v, want %v", err, c.err)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := describe(t); got != c.want {
+					t.Errorf("tasks: got %q, want %q", got, c.want)
+				}
+				if fmt.Sprint(result.Remapped) != fmt.Sprint(c.remapped) || result.Replaced != c.replaced {
+					t.Errorf("result: got %+v", result)
+				}
+				return
+			}
+			if got := describe(t); got != before {
+				t.Errorf("failed import changed the tasks to %q", got)
+			}
+		})
+	}
+}
+
+// TestImportBlocksOnDependencies checks that imported tasks waiting on
+// unfinished dependencies come in blocked, and that the import can be undone
+func TestImportBlocksOnDependencies(t *testing.T) {
+	freshTasks(t, "one")
+	input := "ID,Description,Status,Priority,DependsOn\n" +
+		"1,parent,Pending,High,\n" +
+		"2,child,Pending,Low,1\n"
+	if _, err := tasks.ImportCSV(strings.NewReader(input), tasks.ImportMerge); err != nil {
+		t.Fatal(err)
+	}
+	blocked, _ := tasks.ListTasks(tasks.Filter{Status: "blocked"})
+	if len(blocked) != 1 || blocked[0].Description != "child" || blocked[0].DependsOn[0] != 3 {
+		t.Errorf("blocked: got %+v", blocked)
+	}
+
+	if _, err := tasks.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := describe(t); got != "1 one" {
+		t.Errorf("after undo: got %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"task-manager/tasks"
+)
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+	due, err := tasks.ParseDueDate(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *due
+}
+
+// TestPreviewRecurrence lists the next occurrences of each kind of rule.
+// 2025-01-06 is a Monday.
+func TestPreviewRecurrence(t *testing.T) {
+	cases := []struct {
+		expr string
+		from string
+		want string // "2006-01-02 15:04 Mon" per occurrence
+	}{
+		{"daily", "2024-12-30", "2024-12-31 00:00 Tue, 2025-01-01 00:00 Wed"},
+		{"daily", "2024-02-28", "2024-02-29 00:00 Thu, 2024-03-01 00:00 Fri"},
+		{"every 3 days", "2025-01-06", "2025-01-09 00:00 Thu, 2025-01-12 00:00 Sun, 2025-01-15 00:00 Wed"},
+		{"every 10d", "2025-01-25", "2025-02-04 00:00 Tue, 2025-02-14 00:00 Fri"},
+		{"weekly", "2025-01-06", "2025-01-13 00:00 Mon, 2025-01-20 00:00 Mon"},
+		{"weekly:mon,thu", "2025-01-06", "2025-01-09 00:00 Thu, 2025-01-13 00:00 Mon, 2025-01-16 00:00 Thu"},
+		{"weekly:sun", "2025-01-30", "2025-02-02 00:00 Sun, 2025-02-09 00:00 Sun"},
+		{"cron:0 9 * * 1-5", "2025-01-10", "2025-01-10 09:00 Fri, 2025-01-13 09:00 Mon, 2025-01-14 09:00 Tue"},
+		{"cron:*/20 10 * * *", "2025-01-06", "2025-01-06 10:00 Mon, 2025-01-06 10:20 Mon, 2025-01-06 10:40 Mon, 2025-01-07 10:00 Tue"},
+		{"cron:0 9-17/4 * * *", "2025-01-06", "2025-01-06 09:00 Mon, 2025-01-06 13:00 Mon, 2025-01-06 17:00 Mon"},
+		{"cron:15,45 8 * * *", "2025-01-06", "2025-01-06 08:15 Mon, 2025-01-06 08:45 Mon, 2025-01-07 08:15 Tue"},
+		{"cron:30 8 1 * *", "2025-12-06", "2026-01-01 08:30 Thu, 2026-02-01 08:30 Sun"},
+		{"cron:0 12 31 * *", "2025-01-06", "2025-01-31 12:00 Fri, 2025-03-31 12:00 Mon, 2025-05-31 12:00 Sat"},
+		{"cron:0 0 29 2 *", "2025-01-06", "2028-02-29 00:00 Tue"},
+		{"cron:0 9 * * 7", "2025-01-06", "2025-01-12 09:00 Sun"},
+		// Day of month and day of week both set: either one matches.
+		{"cron:0 9 1 * 0", "2025-01-20", "2025-01-26 09:00 Sun, 2025-02-01 09:00 Sat, 2025-02-02 09:00 Sun"},
+	}
+	for _, c := range cases {
+		n := strings.Count(c.want, ",") + 1
+		occurrences, err := tasks.PreviewRecurrence(c.expr, date(t, c.from), n)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		got := make([]string, len(occurrences))
+		for i, at := range occurrences {
+			got[i] = at.Format("2006-01-02 15:04 Mon")
+		}
+		if strings.Join(got, ", ") != c.want {
+			t.Errorf("%s from %s:\n got %s\nwant %s", c.expr, c.from, strings.Join(got, ", "), c.want)
+		}
+	}
+
+	for _, n := range []int{0, -1} {
+		if occurrences, err := tasks.PreviewRecurrence("daily", date(t, "2025-01-06"), n); err == nil {
+			t.Errorf("%d occurrences: got %v, want an error", n, occurrences)
+		}
+	}
+}
+
+// TestParseRecurrence checks the canonical form and rejects bad rules
+func TestParseRecurrence(t *testing.T) {
+	canonical := map[string]string{
+		"Daily":              "daily",
+		"every 1 day":        "daily",
+		"EVERY 2 days":       "every 2 days",
+		"weekly:Thursday,mo": "", // "mo" is too short for a weekday
+		"weekly:Thu,Mon,thu": "weekly:mon,thu",
+		"cron:0  9 * *   1":  "cron:0 9 * * 1",
+	}
+	for expr, want := range canonical {
+		rule, err := tasks.ParseRecurrence(expr)
+		switch {
+		case want == "" && err == nil:
+			t.Errorf("%q: accepted as %s", expr, rule)
+		case want != "" && (err != nil || rule.String() != want):
+			t.Errorf("%q: got %v, %v; want %s", expr, rule, err, want)
+		}
+	}
+
+	for _, expr := range []string{
+		"hourly", "every 0 days", "every few days", "weekly:funday", "weekly:",
+		"cron:0 9 * *", "cron:60 * * * *", "cron:* 24 * * *", "cron:* * 0 * *",
+		"cron:* * * 13 *", "cron:* * * * 1-8", "cron:*/0 * * * *", "cron:5-1 * * * *", "cron:a * * * *",
+	} {
+		if _, err := tasks.ParseRecurrence(expr); !errors.Is(err, tasks.ErrInvalidRecurrence) {
+			t.Errorf("%q: got %v, want ErrInvalidRecurrence", expr, err)
+		}
+	}
+	if _, err := tasks.PreviewRecurrence("cron:0 0 30 2 *", date(t, "2025-01-01"), 1); !errors.Is(err, tasks.ErrInvalidRecurrence) {
+		t.Errorf("February 30th: got %v, want an error", err)
+	}
+}
+
+// TestCompleteRepeatingTask adds the next occurrence when a repeating task
+// is completed. 2100-01-04 is a Monday.
+func TestCompleteRepeatingTask(t *testing.T) {
+	freshTasks(t)
+	due := date(t, "2100-01-04")
+	task, err := tasks.CreateTask(tasks.TaskSpec{Description: "water plants", Priority: "low", Due: &due, Tags: []string{"home"}, Recurrence: "weekly:thu,mon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"2100-01-07", "2100-01-11", "2100-01-14"} {
+		next, err := tasks.CompleteTask(task.ID)
+		if err != nil || next == nil {
+			t.Fatalf("completing task %d: got %v, %v", task.ID, next, err)
+		}
+		if next.ID != task.ID+1 || tasks.FormatDueDate(next.Due) != want || next.Status != "Pending" ||
+			next.Recurrence != "weekly:mon,thu" || next.Description != "water plants" || !next.HasTag("home") {
+			t.Errorf("next occurrence: got %+v, want due %s", next, want)
+		}
+		task = *next
+	}
+	if got := statuses(t, tasks.Filter{}); got != "1:Completed 2:Completed 3:Completed 4:Pending" {
+		t.Errorf("got %s", got)
+	}
+
+	// Undoing the completion removes the occurrence it added.
+	if _, err := tasks.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if got := statuses(t, tasks.Filter{}); got != "1:Completed 2:Completed 3:Pending" {
+		t.Errorf("after undo: got %s", got)
+	}
+
+	// A task finished late skips the occurrences that are already overdue.
+	past := date(t, "2020-01-01")
+	late, _ := tasks.CreateTask(tasks.TaskSpec{Description: "late", Priority: "low", Due: &past, Recurrence: "every 3 days"})
+	next, err := tasks.CompleteTask(late.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := date(t, time.Now().Format("2006-01-02"))
+	if next.Due.Before(today) || int(next.Due.Sub(past).Hours()/24+0.5)%3 != 0 {
+		t.Errorf("late task: next due %s, want today or later on the 3-day grid", tasks.FormatDueDate(next.Due))
+	}
+
+	// A task that does not repeat adds nothing.
+	once, _ := tasks.CreateTask(tasks.TaskSpec{Description: "once", Priority: "low"})
+	if next, err := tasks.CompleteTask(once.ID); err != nil || next != nil {
+		t.Errorf("one-off task: got %v, %v", next, err)
+	}
+}
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"task-manager/tasks"
+)
+
+// failingStore keeps the last saved snapshot in memory and fails saves on demand
+type failingStore struct {
+	saved tasks.Snapshot
+	fail  bool
+}
+
+func (s *failingStore) Load() (tasks.Snapshot, error) { return s.saved, nil }
+func (s *failingStore) Close() error                  { return nil }
+
+func (s *failingStore) Save(snapshot tasks.Snapshot) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	s.saved = snapshot
+	return nil
+}
+
+// useStore makes s the store of the package-level task list for one test
+func useStore(t *testing.T, s tasks.Store) {
+	t.Helper()
+	if err := tasks.UseStore(s); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { tasks.CloseStore() })
+}
+
+// TestFailedSaveKeepsMemory checks that a change the store rejects is not
+// kept in memory and so can't be written out by a later save
+func TestFailedSaveKeepsMemory(t *testing.T) {
+	store := &failingStore{}
+	useStore(t, store)
+	if _, err := tasks.CreateTask(tasks.TaskSpec{Description: "kept", Priority: "high"}); err != nil {
+		t.Fatal(err)
+	}
+
+	store.fail = true
+	desc := "renamed"
+	failures := map[string]error{}
+	_, failures["add"] = tasks.CreateTask(tasks.TaskSpec{Description: "lost", Priority: "low"})
+	_, failures["edit"] = tasks.EditTask(1, tasks.TaskEdit{Description: &desc})
+	_, failures["complete"] = tasks.CompleteTask(1)
+	failures["delete"] = tasks.DeleteTask(1)
+	_, failures["undo"] = tasks.Undo(1)
+	for op, err := range failures {
+		if err == nil {
+			t.Errorf("%s succeeded although the save failed", op)
+		}
+	}
+
+	list, _ := tasks.ListTasks(tasks.Filter{})
+	if len(list) != 1 || list[0].Description != "kept" || list[0].Status != "Pending" || len(tasks.History()) != 1 {
+		t.Fatalf("memory changed by failed saves: %+v, %d history entries", list, len(tasks.History()))
+	}
+
+	store.fail = false
+	task, err := tasks.CreateTask(tasks.TaskSpec{Description: "next", Priority: "low"})
+	if err != nil || task.ID != 2 {
+		t.Fatalf("got %+v, %v; want task 2", task, err)
+	}
+	if len(store.saved.Tasks) != 2 || store.saved.Tasks[1].Description != "next" || len(store.saved.History) != 2 {
+		t.Errorf("saved %+v", store.saved)
+	}
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"task-assignment/tasks"
+)
+
+// assignRequest is the body of POST /tasks and PATCH /tasks/{id}.
+// A POST without an assignee is auto-assigned using strategy (default weighted).
+type assignRequest struct {
+	Assignee string         `json:"assignee"`
+	Details  string         `json:"details"`
+	Strategy tasks.Strategy `json:"strategy"`
+}
+
+// assigneeRequest is the body of PUT /assignees/{name}
+type assigneeRequest struct {
+	Capacity int `json:"capacity"`
+	Weight   int `json:"weight"`
+}
+
+// errorResponse lists the failed rules when validation fails
+type errorResponse struct {
+	Error    string            `json:"error"`
+	Failures []tasks.RuleError `json:"failures,omitempty"`
+}
+
+// requestError marks a malformed request
+type requestError struct{ msg string }
+
+func (e requestError) Error() string { return e.msg }
+
+// NewHandler serves the assigned tasks and the roster:
+//
+//	GET    /tasks?assignee=     list tasks
+//	POST   /tasks               assign a task, or auto-assign it without an assignee
+//	GET    /tasks/{id}          show one task
+//	PATCH  /tasks/{id}          reassign a task
+//	DELETE /tasks/{id}          complete a task, freeing its slot
+//	GET    /assignees           workload per assignee
+//	PUT    /assignees/{name}    set an assignee's capacity and weight
+func NewHandler(store *tasks.SafeStore) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		list := store.List()
+		if assignee := r.URL.Query().Get("assignee"); assignee != "" {
+			matched := make([]tasks.Task, 0, len(list))
+			for _, task := range list {
+				if strings.EqualFold(task.Assignee, assignee) {
+					matched = append(matched, task)
+				}
+			}
+			list = matched
+		}
+		writeJSON(w, http.StatusOK, list)
+	})
+
+	mux.HandleFunc("POST /tasks", func(w http.ResponseWriter, r *http.Request) {
+		var req assignRequest
+		if err := decode(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		var task tasks.Task
+		var err error
+		if req.Assignee != "" {
+			task, err = store.Assign(req.Assignee, req.Details)
+		} else {
+			if req.Strategy == "" {
+				req.Strategy = tasks.Weighted
+			}
+			task, err = store.AutoAssign(req.Details, req.Strategy)
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/tasks/%d", task.ID))
+		writeJSON(w, http.StatusCreated, task)
+	})
+
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		task, err := store.Get(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	})
+
+	mux.HandleFunc("PATCH /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		var req assignRequest
+		if err := decode(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		if req.Details != "" || req.Strategy != "" {
+			writeError(w, requestError{"only the assignee of a task can be changed"})
+			return
+		}
+		task, err := store.Reassign(id, req.Assignee)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	})
+
+	mux.HandleFunc("DELETE /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		if _, err := store.Remove(id); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /assignees", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, store.Workloads())
+	})
+
+	mux.HandleFunc("PUT /assignees/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var req assigneeRequest
+		if err := decode(r, &req); err != nil {
+			writeError(w, err)
+			return
+		}
+		a, err := store.Register(r.PathValue("name"), req.Capacity, req.Weight)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, assigneeRequest{Capacity: a.Capacity, Weight: a.Weight})
+	})
+	return mux
+}
+
+func decode(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return requestError{"invalid JSON body: " + err.Error()}
+	}
+	return nil
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		return 0, requestError{fmt.Sprintf("invalid task ID %q", r.PathValue("id"))}
+	}
+	return id, nil
+}
+
+// statusCode maps errors from the tasks package to HTTP statuses
+func statusCode(err error) int {
+	var rerr requestError
+	var invalid *tasks.ValidationError
+	switch {
+	case errors.As(err, &rerr),
+		errors.As(err, &invalid),
+		errors.Is(err, tasks.ErrUnknownStrategy):
+		return http.StatusBadRequest
+	case errors.Is(err, tasks.ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, tasks.ErrAtCapacity),
+		errors.Is(err, tasks.ErrAllAtCapacity),
+		errors.Is(err, tasks.ErrSystemFull),
+		errors.Is(err, tasks.ErrNoAssignees):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	resp := errorResponse{Error: err.Error()}
+	var invalid *tasks.ValidationError
+	if errors.As(err, &invalid) {
+		resp.Failures = invalid.Failures
+	}
+	writeJSON(w, statusCode(err), resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
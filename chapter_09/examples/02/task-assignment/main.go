@@ -1,46 +1,93 @@
 package main
 
 import (
-    "bufio"
+    "flag"
     "fmt"
+    "net/http"
     "os"
-    "strings"
+    "strconv"
+    "task-assignment/api"
     "task-assignment/tasks"
 )
 
 func main() {
+    serve := flag.String("serve", "", "serve the REST API on this address (e.g. :8080) instead of the menu")
+    flag.Parse()
+    // TASK_ROSTER names a file of known assignees; only they can be assigned tasks
+    if path := os.Getenv("TASK_ROSTER"); path != "" {
+        names, err := tasks.LoadRoster(path)
+        if err != nil {
+            fmt.Println("Error loading roster:", err)
+            os.Exit(1)
+        }
+        fmt.Printf("Loaded %d assignees from %s.\n", len(names), path)
+    }
+
+    if *serve != "" {
+        fmt.Printf("Serving tasks on %s\n", *serve)
+        if err := http.ListenAndServe(*serve, api.NewHandler(tasks.Default)); err != nil {
+            fmt.Println("Error:", err)
+            os.Exit(1)
+        }
+        return
+    }
+
     tasks.GreetUsers()
-    reader := bufio.NewReader(os.Stdin)
 
     for {
         fmt.Println("Choose an option:")
         fmt.Println("1. Assign a Task")
-        fmt.Println("2. List All Tasks")
-        fmt.Println("3. Exit")
+        fmt.Println("2. Auto-assign a Task")
+        fmt.Println("3. Reassign a Task")
+        fmt.Println("4. List All Tasks")
+        fmt.Println("5. Set Assignee Capacity")
+        fmt.Println("6. Exit")
         fmt.Print("Enter your choice: ")
-        choice, _ := reader.ReadString('\n')
-        choice = strings.TrimSpace(choice)
+        choice := tasks.ReadLine("")
 
         switch choice {
         case "1":
             assignee, taskDetails := tasks.GetUserInput()
-            isValidAssignee, isValidTask := tasks.ValidateUserInput(assignee, taskDetails)
-
-            if isValidAssignee && isValidTask {
-                tasks.AssignTask(assignee, taskDetails)
-            } else {
-                if !isValidAssignee {
-                    fmt.Println("The assignee name is too short or invalid.")
-                }
-                if !isValidTask {
-                    fmt.Println("The task description is too short or invalid.")
-                }
+            if err := tasks.ValidateUserInput(assignee, taskDetails); err != nil {
+                tasks.PrintValidationError(err)
+                break
             }
+            tasks.AssignTask(assignee, taskDetails)
 
         case "2":
-            tasks.ListAllTasks()
+            taskDetails := tasks.ReadLine("Enter the task details:")
+            if err := tasks.ValidateDetails(taskDetails); err != nil {
+                tasks.PrintValidationError(err)
+                break
+            }
+            tasks.AutoAssignTask(taskDetails, tasks.GetStrategyInput())
 
         case "3":
+            id, err := strconv.Atoi(tasks.ReadLine("Enter the task ID to reassign:"))
+            if err != nil {
+                fmt.Println("Invalid task ID.")
+                break
+            }
+            assignee := tasks.ReadLine("Enter the new assignee name:")
+            if err := tasks.ValidateAssignee(assignee); err != nil {
+                tasks.PrintValidationError(err)
+                break
+            }
+            tasks.ReassignTask(id, assignee)
+
+        case "4":
+            tasks.ListAllTasks()
+
+        case "5":
+            name, capacity, weight := tasks.GetCapacityInput()
+            a, err := tasks.RegisterAssignee(name, capacity, weight)
+            if err != nil {
+                tasks.PrintValidationError(err)
+                break
+            }
+            fmt.Printf("%s can hold up to %d tasks (weight %d).\n", a.Name, a.Capacity, a.Weight)
+
+        case "6":
             fmt.Println("Exiting the Task Assignment System. Goodbye!")
             return
 
@@ -0,0 +1,164 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultCapacity is the task limit for assignees registered without one
+var DefaultCapacity = 3
+
+var (
+	ErrAtCapacity      = errors.New("assignee is at capacity")
+	ErrSystemFull      = errors.New("the maximum number of tasks has been assigned")
+	ErrNoAssignees     = errors.New("no assignees registered")
+	ErrAllAtCapacity   = errors.New("every assignee is at capacity")
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrUnknownStrategy = errors.New("unknown strategy, choose round-robin or weighted")
+	ErrNoEligible      = errors.New("no assignee with room can take this task")
+)
+
+// Assignee is someone who can receive tasks
+type Assignee struct {
+	Name     string
+	Capacity int // most open tasks at once
+	Weight   int // relative share for weighted auto-assignment
+}
+
+// Strategy picks who receives an automatically assigned task
+type Strategy string
+
+const (
+	RoundRobin Strategy = "round-robin" // lowest load, ties go to the next in roster order
+	Weighted   Strategy = "weighted"    // lowest load relative to weight
+)
+
+// RegisterAssignee adds someone to the roster of Default or updates their
+// limits. A capacity or weight below 1 falls back to DefaultCapacity and 1.
+func RegisterAssignee(name string, capacity, weight int) (Assignee, error) {
+	return Default.Register(name, capacity, weight)
+}
+
+// register adds or updates an assignee without checking the name
+func (s *SafeStore) register(name string, capacity, weight int) *Assignee {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	if weight < 1 {
+		weight = 1
+	}
+
+	if a := s.findAssignee(name); a != nil {
+		a.Capacity = capacity
+		a.Weight = weight
+		return a
+	}
+	a := &Assignee{Name: name, Capacity: capacity, Weight: weight}
+	s.assignees = append(s.assignees, a)
+	return a
+}
+
+// findAssignee looks a name up in the roster, ignoring case
+func (s *SafeStore) findAssignee(name string) *Assignee {
+	for _, a := range s.assignees {
+		if strings.EqualFold(a.Name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+// load counts the tasks currently held by name
+func (s *SafeStore) load(name string) int {
+	count := 0
+	for _, task := range s.tasks {
+		if strings.EqualFold(task.Assignee, name) {
+			count++
+		}
+	}
+	return count
+}
+
+// pickAssignee chooses who gets the next automatically assigned task,
+// passing over anyone in skip
+func (s *SafeStore) pickAssignee(strategy Strategy, skip map[*Assignee]bool) (*Assignee, error) {
+	if strategy != RoundRobin && strategy != Weighted {
+		return nil, ErrUnknownStrategy
+	}
+	if len(s.assignees) == 0 {
+		return nil, ErrNoAssignees
+	}
+
+	switch strategy {
+	case RoundRobin:
+		// Walking the roster from the turn, the first of several equally
+		// loaded assignees is the one whose turn comes first.
+		best, bestLoad := -1, 0
+		for step := 1; step <= len(s.assignees); step++ {
+			i := (s.lastRoundRobin + step) % len(s.assignees)
+			a := s.assignees[i]
+			l := s.load(a.Name)
+			if skip[a] || l >= a.Capacity {
+				continue
+			}
+			if best < 0 || l < bestLoad {
+				best, bestLoad = i, l
+			}
+		}
+		if best >= 0 {
+			s.lastRoundRobin = best
+			return s.assignees[best], nil
+		}
+	case Weighted:
+		var best *Assignee
+		var bestLoad int
+		for _, a := range s.assignees {
+			l := s.load(a.Name)
+			if skip[a] || l >= a.Capacity {
+				continue
+			}
+			// l/a.Weight < bestLoad/best.Weight, without integer division
+			if best == nil || l*best.Weight < bestLoad*a.Weight {
+				best, bestLoad = a, l
+			}
+		}
+		if best != nil {
+			return best, nil
+		}
+	}
+	return nil, ErrAllAtCapacity
+}
+
+// Workload is one line of the per-assignee report
+type Workload struct {
+	Assignee string `json:"assignee"`
+	Tasks    int    `json:"tasks"`
+	Capacity int    `json:"capacity"`
+	Weight   int    `json:"weight"`
+}
+
+// Workloads reports the load of every assignee of Default, in roster order
+func Workloads() []Workload {
+	return Default.Workloads()
+}
+
+func (s *SafeStore) workloads() []Workload {
+	report := make([]Workload, 0, len(s.assignees))
+	for _, a := range s.assignees {
+		report = append(report, Workload{Assignee: a.Name, Tasks: s.load(a.Name), Capacity: a.Capacity, Weight: a.Weight})
+	}
+	return report
+}
+
+// capacityOf returns the task limit for name, registered or not
+func (s *SafeStore) capacityOf(name string) int {
+	if a := s.findAssignee(name); a != nil {
+		return a.Capacity
+	}
+	return DefaultCapacity
+}
+
+func formatWorkload(w Workload) string {
+	return fmt.Sprintf("%s: %d/%d tasks (weight %d)", w.Assignee, w.Tasks, w.Capacity, w.Weight)
+}
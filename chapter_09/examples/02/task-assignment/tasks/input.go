@@ -4,20 +4,42 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 )
 
+// reader is shared so buffered input is not lost between prompts
+var reader = bufio.NewReader(os.Stdin)
+
+// ReadLine prints prompt on its own line and returns the trimmed answer
+func ReadLine(prompt string) string {
+	if prompt != "" {
+		fmt.Println(prompt)
+	}
+	line, _ := reader.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
 // GetUserInput collects assignee name and task details from the user
 func GetUserInput() (string, string) {
-	reader := bufio.NewReader(os.Stdin)
-
-	fmt.Println("Enter the assignee name:")
-	assignee, _ := reader.ReadString('\n')
-	assignee = strings.TrimSpace(assignee)
+	assignee := ReadLine("Enter the assignee name:")
+	details := ReadLine("Enter the task details:")
+	return assignee, details
+}
 
-	fmt.Println("Enter the task details:")
-	details, _ := reader.ReadString('\n')
-	details = strings.TrimSpace(details)
+// GetCapacityInput collects an assignee name with their capacity and weight
+func GetCapacityInput() (string, int, int) {
+	name := ReadLine("Enter the assignee name:")
+	capacity, _ := strconv.Atoi(ReadLine(fmt.Sprintf("Enter the maximum number of open tasks (blank for %d):", DefaultCapacity)))
+	weight, _ := strconv.Atoi(ReadLine("Enter the weight for auto-assignment (blank for 1):"))
+	return name, capacity, weight
+}
 
-	return assignee, details
+// GetStrategyInput asks which auto-assignment strategy to use
+func GetStrategyInput() Strategy {
+	answer := strings.ToLower(ReadLine("Choose a strategy (round-robin, weighted) [weighted]:"))
+	if answer == string(RoundRobin) || answer == "rr" {
+		return RoundRobin
+	}
+	return Weighted
 }
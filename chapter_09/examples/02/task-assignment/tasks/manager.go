@@ -2,44 +2,193 @@ package tasks
 
 import (
 	"fmt"
+	"strings"
 )
 
-// AssignTask assigns a new task to the task list
-func AssignTask(assignee string, details string) {
+// Assign adds a task for assignee in Default if the input passes the
+// current rules and both the system and the assignee have room
+func Assign(assignee string, details string) (Task, error) {
+	return Default.Assign(assignee, details)
+}
+
+func (s *SafeStore) assign(assignee string, details string) (Task, error) {
+	if err := s.validate(assignee, details); err != nil {
+		return Task{}, err
+	}
+	if s.full() {
+		return Task{}, ErrSystemFull
+	}
+	if s.load(assignee) >= s.capacityOf(assignee) {
+		return Task{}, fmt.Errorf("%s: %w (%d tasks)", assignee, ErrAtCapacity, s.capacityOf(assignee))
+	}
+
+	// Anyone who receives a task joins the roster, so auto-assignment can pick them later.
+	if s.findAssignee(assignee) == nil {
+		s.register(assignee, 0, 0)
+	}
+
 	task := Task{
-		ID:       nextTaskID,
+		ID:       s.nextID,
 		Assignee: assignee,
 		Details:  details,
 	}
-	tasks = append(tasks, task)
-	nextTaskID++
+	s.tasks = append(s.tasks, task)
+	s.nextID++
+	return task, nil
+}
 
-	fmt.Printf("Task assigned to %s: %s (Task ID: %d)\n", assignee, details, task.ID)
-	fmt.Printf("%d tasks remaining to assign.\n", maxTasks-len(tasks))
+// AutoAssign hands the task to whoever the strategy picks in Default. If the
+// rules turn that person down, e.g. because they already hold the same task,
+// the next pick gets it; ErrNoEligible means everyone with room was turned
+// down. A task that is rejected does not use up anyone's round-robin turn.
+func AutoAssign(details string, strategy Strategy) (Task, error) {
+	return Default.AutoAssign(details, strategy)
 }
 
-// ListAllTasks lists all the assigned tasks
+func (s *SafeStore) autoAssign(details string, strategy Strategy) (Task, error) {
+	var c checks
+	s.rules.checkDetails(&c, details)
+	if err := c.err(); err != nil {
+		return Task{}, err
+	}
+	if s.full() {
+		return Task{}, ErrSystemFull
+	}
+	turn := s.lastRoundRobin
+	skip := make(map[*Assignee]bool)
+	// Every refusal stays wrapped, so errors.As still finds the *ValidationError.
+	format, refusals := "%w", []any{ErrNoEligible}
+	for {
+		a, err := s.pickAssignee(strategy, skip)
+		if err != nil {
+			s.lastRoundRobin = turn
+			if len(refusals) > 1 {
+				return Task{}, fmt.Errorf(format, refusals...)
+			}
+			return Task{}, err
+		}
+		task, err := s.assign(a.Name, details)
+		if err == nil {
+			return task, nil
+		}
+		skip[a] = true
+		format += "; %s: %w"
+		refusals = append(refusals, a.Name, err)
+	}
+}
+
+// Reassign moves a task in Default to another assignee, who must pass the
+// current rules, not already hold the same task and have room for it
+func Reassign(id int, assignee string) (Task, error) {
+	return Default.Reassign(id, assignee)
+}
+
+func (s *SafeStore) reassign(id int, assignee string) (Task, error) {
+	for i, task := range s.tasks {
+		if task.ID != id {
+			continue
+		}
+		if strings.EqualFold(task.Assignee, assignee) {
+			return task, nil
+		}
+		var c checks
+		s.rules.checkAssignee(&c, assignee)
+		s.checkDuplicate(&c, assignee, task.Details)
+		if err := c.err(); err != nil {
+			return Task{}, err
+		}
+		if s.load(assignee) >= s.capacityOf(assignee) {
+			return Task{}, fmt.Errorf("%s: %w (%d tasks)", assignee, ErrAtCapacity, s.capacityOf(assignee))
+		}
+		if s.findAssignee(assignee) == nil {
+			s.register(assignee, 0, 0)
+		}
+		s.tasks[i].Assignee = assignee
+		return s.tasks[i], nil
+	}
+	return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+// Remove takes a finished or cancelled task off the list of Default,
+// freeing its assignee's slot
+func Remove(id int) (Task, error) {
+	return Default.Remove(id)
+}
+
+func (s *SafeStore) remove(id int) (Task, error) {
+	for i, task := range s.tasks {
+		if task.ID == id {
+			s.tasks = append(s.tasks[:i:i], s.tasks[i+1:]...)
+			return task, nil
+		}
+	}
+	return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+// AssignTask assigns a new task to the task list
+func AssignTask(assignee string, details string) {
+	task, err := Assign(assignee, details)
+	if err != nil {
+		fmt.Println("Could not assign task:", err)
+		return
+	}
+	printAssigned(task)
+}
+
+// AutoAssignTask assigns a new task to the assignee picked by strategy
+func AutoAssignTask(details string, strategy Strategy) {
+	task, err := AutoAssign(details, strategy)
+	if err != nil {
+		fmt.Println("Could not assign task:", err)
+		return
+	}
+	printAssigned(task)
+}
+
+// ReassignTask moves a task to a different assignee
+func ReassignTask(id int, assignee string) {
+	task, err := Reassign(id, assignee)
+	if err != nil {
+		fmt.Println("Could not reassign task:", err)
+		return
+	}
+	fmt.Printf("Task %d reassigned to %s.\n", task.ID, task.Assignee)
+}
+
+func printAssigned(task Task) {
+	fmt.Printf("Task assigned to %s: %s (Task ID: %d)\n", task.Assignee, task.Details, task.ID)
+	if limit := Default.MaxTasks(); limit > 0 {
+		fmt.Printf("%d tasks remaining to assign.\n", limit-Default.Len())
+	}
+}
+
+// ListAllTasks lists all the assigned tasks and the workload per assignee
 func ListAllTasks() {
-	if len(tasks) == 0 {
+	list := Default.List()
+	if len(list) == 0 {
 		fmt.Println("No tasks have been assigned yet.")
-		return
+	} else {
+		fmt.Println("List of Assigned Tasks:")
+		for _, task := range list {
+			fmt.Printf("Task ID: %d, Assignee: %s, Details: %s\n", task.ID, task.Assignee, task.Details)
+		}
 	}
 
-	fmt.Println("List of Assigned Tasks:")
-	for _, task := range tasks {
-		fmt.Printf("Task ID: %d, Assignee: %s, Details: %s\n", task.ID, task.Assignee, task.Details)
+	report := Workloads()
+	if len(report) == 0 {
+		return
+	}
+	fmt.Println("Workload per Assignee:")
+	for _, w := range report {
+		fmt.Println(formatWorkload(w))
 	}
 }
 
 // GreetUsers welcomes the user to the task assignment system
 func GreetUsers() {
 	fmt.Printf("Welcome to the Task Assignment System.\n")
-	fmt.Printf("You can assign up to %d tasks.\n", maxTasks)
-}
-
-// ValidateUserInput validates the assignee name and task details
-func ValidateUserInput(assignee string, details string) (bool, bool) {
-	isValidAssignee := len(assignee) >= 2
-	isValidTask := len(details) >= 5
-	return isValidAssignee, isValidTask
+	if limit := Default.MaxTasks(); limit > 0 {
+		fmt.Printf("You can assign up to %d tasks.\n", limit)
+	}
+	fmt.Printf("Each assignee can hold up to %d tasks unless their capacity is set.\n", DefaultCapacity)
 }
@@ -0,0 +1,145 @@
+package tasks
+
+import (
+	"fmt"
+	"sync"
+)
+
+// SafeStore holds the assigned tasks, the roster and the validation rules
+// behind one lock, so a single SafeStore can be shared between goroutines,
+// e.g. HTTP handlers. The package functions (Assign, ListAllTasks, the
+// validation helpers, ...) all work on Default.
+type SafeStore struct {
+	mu             sync.RWMutex
+	tasks          []Task
+	nextID         int
+	assignees      []*Assignee // the roster, in registration order
+	lastRoundRobin int         // roster index that received the last round-robin task
+	maxTasks       int
+	rules          Rules
+}
+
+// NewSafeStore returns an empty store checked against DefaultRules. maxTasks
+// caps the whole system; 0 leaves only the per-assignee capacities.
+func NewSafeStore(maxTasks int) *SafeStore {
+	return &SafeStore{
+		tasks:          make([]Task, 0),
+		nextID:         1,
+		assignees:      make([]*Assignee, 0),
+		lastRoundRobin: -1,
+		maxTasks:       maxTasks,
+		rules:          DefaultRules,
+	}
+}
+
+// Default is the store behind the package functions, the menu and -serve
+var Default = NewSafeStore(DefaultMaxTasks)
+
+// Assign validates and assigns a task in one step, see Assign
+func (s *SafeStore) Assign(assignee, details string) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.assign(assignee, details)
+}
+
+// AutoAssign hands the task to whoever strategy picks, see AutoAssign
+func (s *SafeStore) AutoAssign(details string, strategy Strategy) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.autoAssign(details, strategy)
+}
+
+// Reassign moves a task to a valid assignee with room for it, see Reassign
+func (s *SafeStore) Reassign(id int, assignee string) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reassign(id, assignee)
+}
+
+// Remove takes a task off the list, see Remove
+func (s *SafeStore) Remove(id int) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(id)
+}
+
+// List returns a copy of the assigned tasks
+func (s *SafeStore) List() []Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Task, len(s.tasks))
+	copy(list, s.tasks)
+	return list
+}
+
+// Len returns the number of assigned tasks
+func (s *SafeStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tasks)
+}
+
+// MaxTasks returns the cap on the whole system, 0 for none
+func (s *SafeStore) MaxTasks() int {
+	return s.maxTasks
+}
+
+// Full reports whether the cap on the whole system has been reached
+func (s *SafeStore) Full() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.full()
+}
+
+// Get returns one task
+func (s *SafeStore) Get(id int) (Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, task := range s.tasks {
+		if task.ID == id {
+			return task, nil
+		}
+	}
+	return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+// Register validates the name and adds or updates an assignee, see RegisterAssignee
+func (s *SafeStore) Register(name string, capacity, weight int) (Assignee, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var c checks
+	s.rules.checkAssignee(&c, name)
+	if err := c.err(); err != nil {
+		return Assignee{}, err
+	}
+	return *s.register(name, capacity, weight), nil
+}
+
+// Workloads reports the load of every assignee, see Workloads
+func (s *SafeStore) Workloads() []Workload {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.workloads()
+}
+
+// Validate checks a new task against every rule, duplicates included,
+// see ValidateUserInput
+func (s *SafeStore) Validate(assignee, details string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.validate(assignee, details)
+}
+
+// SetRules replaces the rules new tasks are checked against
+func (s *SafeStore) SetRules(r Rules) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rules = r
+}
+
+// CurrentRules returns the rules new tasks are checked against
+func (s *SafeStore) CurrentRules() Rules {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rules
+}
@@ -2,16 +2,20 @@ package tasks
 
 // Task represents a task assigned to an individual
 type Task struct {
-	ID       int
-	Assignee string
-	Details  string
+	ID       int    `json:"id"`
+	Assignee string `json:"assignee"`
+	Details  string `json:"details"`
 }
 
-var tasks = make([]Task, 0)
-var nextTaskID = 1
-const maxTasks = 10
+// DefaultMaxTasks caps the whole system of Default; 0 would leave only the
+// per-assignee capacities
+const DefaultMaxTasks = 10
 
 // AllTasksAssigned checks if the maximum tasks have been assigned
 func AllTasksAssigned() bool {
-	return len(tasks) >= maxTasks
+	return Default.Full()
+}
+
+func (s *SafeStore) full() bool {
+	return s.maxTasks > 0 && len(s.tasks) >= s.maxTasks
 }
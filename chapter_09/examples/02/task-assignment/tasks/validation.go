@@ -0,0 +1,319 @@
+package tasks
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CharPolicy decides which characters an assignee name may contain
+type CharPolicy struct {
+	Name  string
+	Allow func(r rune) bool
+}
+
+var (
+	// LettersOnly allows letters in any script plus space, hyphen, apostrophe and dot ("Anne-Marie O'Neil")
+	LettersOnly = CharPolicy{"letters, spaces, - ' .", func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsMark(r) || strings.ContainsRune(" -'.", r)
+	}}
+	// LettersAndDigits also allows digits and underscores, for handles like "dev_42"
+	LettersAndDigits = CharPolicy{"letters, digits, spaces, - ' . _", func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || strings.ContainsRune(" -'._", r)
+	}}
+	// AnyPrintable only rejects control and other non-printable characters
+	AnyPrintable = CharPolicy{"printable characters", unicode.IsPrint}
+)
+
+// Rules is the configurable set of checks applied to new tasks.
+// Lengths count characters, not bytes; a zero maximum means no limit.
+type Rules struct {
+	MinAssigneeLen   int
+	MaxAssigneeLen   int
+	MinDetailsLen    int
+	MaxDetailsLen    int
+	AssigneeChars    CharPolicy
+	KnownAssignees   []string // when set, only these names are accepted
+	RejectDuplicates bool     // same details already assigned to the same person
+}
+
+// DefaultRules keeps the original minimums and adds sensible limits
+var DefaultRules = Rules{
+	MinAssigneeLen:   2,
+	MaxAssigneeLen:   50,
+	MinDetailsLen:    5,
+	MaxDetailsLen:    500,
+	AssigneeChars:    LettersOnly,
+	RejectDuplicates: true,
+}
+
+// SetRules replaces the rules of Default, used by ValidateUserInput
+func SetRules(r Rules) {
+	Default.SetRules(r)
+}
+
+// CurrentRules returns the rules of Default, used by ValidateUserInput
+func CurrentRules() Rules {
+	return Default.CurrentRules()
+}
+
+// RuleError is one failed check
+type RuleError struct {
+	Field   string `json:"field"` // "assignee" or "details"
+	Rule    string `json:"rule"`  // "min_length", "max_length", "characters", "known_assignee" or "duplicate"
+	Message string `json:"message"`
+}
+
+func (e RuleError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every rule that failed
+type ValidationError struct {
+	Failures []RuleError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		messages[i] = failure.Error()
+	}
+	return "invalid input: " + strings.Join(messages, "; ")
+}
+
+// Has reports whether the given field failed the given rule
+func (e *ValidationError) Has(field, rule string) bool {
+	for _, failure := range e.Failures {
+		if failure.Field == field && failure.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// checks collects failures and turns them into a *ValidationError
+type checks []RuleError
+
+func (c *checks) fail(field, rule, format string, args ...any) {
+	*c = append(*c, RuleError{Field: field, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c checks) err() error {
+	if len(c) == 0 {
+		return nil
+	}
+	return &ValidationError{Failures: c}
+}
+
+func (c *checks) length(field, value string, min, max int) {
+	n := utf8.RuneCountInString(value)
+	if n < min {
+		c.fail(field, "min_length", "must be at least %d characters (got %d)", min, n)
+	}
+	if max > 0 && n > max {
+		c.fail(field, "max_length", "must be at most %d characters (got %d)", max, n)
+	}
+}
+
+func (r Rules) checkAssignee(c *checks, assignee string) {
+	c.length("assignee", assignee, r.MinAssigneeLen, r.MaxAssigneeLen)
+
+	if r.AssigneeChars.Allow != nil {
+		var bad []string
+		for _, ch := range assignee {
+			if !r.AssigneeChars.Allow(ch) {
+				bad = append(bad, strconv.QuoteRune(ch))
+			}
+		}
+		if len(bad) > 0 {
+			c.fail("assignee", "characters", "contains %s; allowed: %s", strings.Join(bad, " "), r.AssigneeChars.Name)
+		}
+	}
+
+	if len(r.KnownAssignees) > 0 {
+		known := false
+		for _, name := range r.KnownAssignees {
+			if strings.EqualFold(name, assignee) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.fail("assignee", "known_assignee", "%q is not on the roster", assignee)
+		}
+	}
+}
+
+func (r Rules) checkDetails(c *checks, details string) {
+	c.length("details", details, r.MinDetailsLen, r.MaxDetailsLen)
+
+	for _, ch := range details {
+		if !unicode.IsPrint(ch) {
+			c.fail("details", "characters", "contains non-printable character %s", strconv.QuoteRune(ch))
+			break
+		}
+	}
+}
+
+// checkDuplicate applies RejectDuplicates, which needs the assigned tasks
+func (s *SafeStore) checkDuplicate(c *checks, assignee, details string) {
+	if !s.rules.RejectDuplicates {
+		return
+	}
+	if task, ok := s.findDuplicate(assignee, details); ok {
+		c.fail("details", "duplicate", "%s already has this task (Task ID: %d)", task.Assignee, task.ID)
+	}
+}
+
+// Validate checks a new task against every rule that needs only the input
+// and reports all failures. RejectDuplicates is applied by SafeStore.Validate.
+func (r Rules) Validate(assignee, details string) error {
+	var c checks
+	r.checkAssignee(&c, assignee)
+	r.checkDetails(&c, details)
+	return c.err()
+}
+
+// validate checks a new task against every rule, duplicates included
+func (s *SafeStore) validate(assignee, details string) error {
+	var c checks
+	s.rules.checkAssignee(&c, assignee)
+	s.rules.checkDetails(&c, details)
+	s.checkDuplicate(&c, assignee, details)
+	return c.err()
+}
+
+// ValidateUserInput validates the assignee name and task details
+// against the current rules of Default, listing every rule that failed
+func ValidateUserInput(assignee string, details string) error {
+	return Default.Validate(assignee, details)
+}
+
+// ValidateAssignee checks an assignee name on its own
+func ValidateAssignee(assignee string) error {
+	var c checks
+	rules := CurrentRules()
+	rules.checkAssignee(&c, assignee)
+	return c.err()
+}
+
+// ValidateDetails checks task details on their own
+func ValidateDetails(details string) error {
+	var c checks
+	rules := CurrentRules()
+	rules.checkDetails(&c, details)
+	return c.err()
+}
+
+// findDuplicate looks for the same details already assigned to assignee,
+// ignoring case and repeated whitespace
+func (s *SafeStore) findDuplicate(assignee, details string) (Task, bool) {
+	key := normalizeDetails(details)
+	for _, task := range s.tasks {
+		if strings.EqualFold(task.Assignee, assignee) && normalizeDetails(task.Details) == key {
+			return task, true
+		}
+	}
+	return Task{}, false
+}
+
+func normalizeDetails(details string) string {
+	return strings.ToLower(strings.Join(strings.Fields(details), " "))
+}
+
+// LoadRoster reads the known assignees from a file, one per line:
+//
+//	# name[,capacity[,weight]]
+//	Alice,2
+//	Bob,3,2
+//
+// Everyone listed is registered in Default with their capacity and weight,
+// and the validation rules are limited to these names. A file listing
+// nobody is an error, since an empty roster would lift the limit instead.
+func LoadRoster(path string) ([]string, error) {
+	return Default.LoadRoster(path)
+}
+
+// LoadRoster reads the known assignees from a file, see the LoadRoster function
+func (s *SafeStore) LoadRoster(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type entry struct {
+		name             string
+		capacity, weight int
+	}
+	// Names are checked against the other rules only; the roster being
+	// loaded replaces any earlier one.
+	check := s.CurrentRules()
+	check.KnownAssignees = nil
+
+	var entries []entry
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ",")
+		e := entry{name: strings.TrimSpace(fields[0])}
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: expected name[,capacity[,weight]]", path, lineNo)
+		}
+		if len(fields) > 1 {
+			if e.capacity, err = strconv.Atoi(strings.TrimSpace(fields[1])); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid capacity %q", path, lineNo, fields[1])
+			}
+		}
+		if len(fields) > 2 {
+			if e.weight, err = strconv.Atoi(strings.TrimSpace(fields[2])); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid weight %q", path, lineNo, fields[2])
+			}
+		}
+
+		var c checks
+		check.checkAssignee(&c, e.name)
+		if err := c.err(); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s: no assignees listed", path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		s.register(e.name, e.capacity, e.weight)
+		names[i] = e.name
+	}
+	s.rules.KnownAssignees = names
+	return names, nil
+}
+
+// PrintValidationError prints each failed rule on its own line
+func PrintValidationError(err error) {
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		fmt.Println("Error:", err)
+		return
+	}
+	fmt.Println("The task was not accepted:")
+	for _, failure := range invalid.Failures {
+		fmt.Println(" -", failure.Error())
+	}
+}
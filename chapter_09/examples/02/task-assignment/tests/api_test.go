@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"task-assignment/api"
+	"task-assignment/tasks"
+)
+
+// newServer starts the API on an empty task list with the overall cap lifted
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	store := tasks.NewSafeStore(0)
+	server := httptest.NewServer(api.NewHandler(store))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// call sends a JSON request and decodes the JSON response into out, if given
+func call(t *testing.T, method, url string, body any, out any) int {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Error(err)
+		return 0
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Errorf("%s %s: decoding response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// parallel runs fn n times at once and counts the status codes it returns
+func parallel(n int, fn func(i int) int) map[int]int {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	codes := make(map[int]int)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code := fn(i)
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	return codes
+}
+
+// TestAssignLifecycle assigns, reassigns and completes a task
+func TestAssignLifecycle(t *testing.T) {
+	server := newServer(t)
+
+	var task tasks.Task
+	if code := call(t, "POST", server.URL+"/tasks", map[string]string{"assignee": "Alice", "details": "Write the report"}, &task); code != http.StatusCreated {
+		t.Fatalf("POST /tasks: got %d, want 201", code)
+	}
+	if task.Assignee != "Alice" || task.Details != "Write the report" {
+		t.Fatalf("unexpected task %+v", task)
+	}
+
+	url := fmt.Sprintf("%s/tasks/%d", server.URL, task.ID)
+	if code := call(t, "PATCH", url, map[string]string{"assignee": "Bob"}, &task); code != http.StatusOK || task.Assignee != "Bob" {
+		t.Fatalf("PATCH: got %d, assignee %q", code, task.Assignee)
+	}
+
+	var list []tasks.Task
+	if code := call(t, "GET", server.URL+"/tasks?assignee=bob", nil, &list); code != http.StatusOK || len(list) != 1 {
+		t.Fatalf("GET /tasks?assignee=bob: got %d with %d tasks", code, len(list))
+	}
+
+	if code := call(t, "DELETE", url, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("DELETE: got %d, want 204", code)
+	}
+	if code := call(t, "GET", url, nil, nil); code != http.StatusNotFound {
+		t.Errorf("GET completed task: got %d, want 404", code)
+	}
+}
+
+// TestValidationFailures checks that every failed rule is reported
+func TestValidationFailures(t *testing.T) {
+	server := newServer(t)
+
+	var resp struct {
+		Failures []tasks.RuleError `json:"failures"`
+	}
+	code := call(t, "POST", server.URL+"/tasks", map[string]string{"assignee": "B0b!", "details": "hi"}, &resp)
+	if code != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", code)
+	}
+	if len(resp.Failures) != 2 {
+		t.Errorf("got failures %+v, want a characters and a min_length failure", resp.Failures)
+	}
+
+	if code := call(t, "POST", server.URL+"/tasks", map[string]string{"details": "Needs a strategy", "strategy": "random"}, nil); code != http.StatusBadRequest {
+		t.Errorf("unknown strategy: got %d, want 400", code)
+	}
+}
+
+// TestConcurrentAssign has many clients race for the same assignee;
+// exactly their capacity may succeed
+func TestConcurrentAssign(t *testing.T) {
+	server := newServer(t)
+	const capacity, clients = 5, 40
+
+	if code := call(t, "PUT", server.URL+"/assignees/Carol", map[string]int{"capacity": capacity}, nil); code != http.StatusOK {
+		t.Fatalf("PUT /assignees/Carol: got %d", code)
+	}
+
+	codes := parallel(clients, func(i int) int {
+		body := map[string]string{"assignee": "Carol", "details": fmt.Sprintf("Concurrent task %d", i)}
+		return call(t, "POST", server.URL+"/tasks", body, nil)
+	})
+	if codes[http.StatusCreated] != capacity || codes[http.StatusConflict] != clients-capacity {
+		t.Fatalf("got status counts %v, want %d created and %d conflicts", codes, capacity, clients-capacity)
+	}
+
+	var list []tasks.Task
+	call(t, "GET", server.URL+"/tasks?assignee=Carol", nil, &list)
+	seen := make(map[int]bool)
+	for _, task := range list {
+		if seen[task.ID] {
+			t.Errorf("ID %d handed out twice", task.ID)
+		}
+		seen[task.ID] = true
+	}
+
+	// Each task is completed by two clients at once; only one can win.
+	codes = parallel(2*len(list), func(i int) int {
+		return call(t, "DELETE", fmt.Sprintf("%s/tasks/%d", server.URL, list[i/2].ID), nil, nil)
+	})
+	if codes[http.StatusNoContent] != len(list) || codes[http.StatusNotFound] != len(list) {
+		t.Errorf("got status counts %v, want %d completed and %d not found", codes, len(list), len(list))
+	}
+}
+
+// TestConcurrentAutoAssign checks that concurrent auto-assignment and
+// reassignment never push anyone past their capacity
+func TestConcurrentAutoAssign(t *testing.T) {
+	server := newServer(t)
+
+	for _, name := range []string{"Dave", "Erin"} {
+		call(t, "PUT", server.URL+"/assignees/"+name, map[string]int{"capacity": 4, "weight": 2}, nil)
+	}
+	call(t, "PUT", server.URL+"/assignees/Frank", map[string]int{"capacity": 1}, nil)
+
+	var before []tasks.Workload
+	call(t, "GET", server.URL+"/assignees", nil, &before)
+	free := 0
+	for _, w := range before {
+		free += w.Capacity - w.Tasks
+	}
+
+	codes := parallel(free+10, func(i int) int {
+		body := map[string]string{"details": fmt.Sprintf("Auto task %d", i), "strategy": string([]tasks.Strategy{tasks.RoundRobin, tasks.Weighted}[i%2])}
+		return call(t, "POST", server.URL+"/tasks", body, nil)
+	})
+	if codes[http.StatusCreated] != free || codes[http.StatusConflict] != 10 {
+		t.Errorf("got status counts %v, want %d created and 10 conflicts", codes, free)
+	}
+
+	// Free up one of Dave's slots, then race all of Erin's tasks for it.
+	var daves []tasks.Task
+	call(t, "GET", server.URL+"/tasks?assignee=Dave", nil, &daves)
+	call(t, "DELETE", fmt.Sprintf("%s/tasks/%d", server.URL, daves[0].ID), nil, nil)
+	var erins []tasks.Task
+	call(t, "GET", server.URL+"/tasks?assignee=Erin", nil, &erins)
+	codes = parallel(len(erins), func(i int) int {
+		return call(t, "PATCH", fmt.Sprintf("%s/tasks/%d", server.URL, erins[i].ID), map[string]string{"assignee": "Dave"}, nil)
+	})
+	if codes[http.StatusOK] != 1 {
+		t.Errorf("reassigning to one free slot: got status counts %v, want exactly one success", codes)
+	}
+
+	var after []tasks.Workload
+	call(t, "GET", server.URL+"/assignees", nil, &after)
+	for _, w := range after {
+		if w.Tasks > w.Capacity {
+			t.Errorf("%s holds %d tasks, capacity %d", w.Assignee, w.Tasks, w.Capacity)
+		}
+	}
+}
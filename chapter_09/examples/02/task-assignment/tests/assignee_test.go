@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"task-assignment/tasks"
+)
+
+// autoTasks numbers the auto-assigned tasks so no two have the same details
+var autoTasks int
+
+// autoAssign assigns n tasks with strategy and lists who received each one
+func autoAssign(t *testing.T, store *tasks.SafeStore, n int, strategy tasks.Strategy) string {
+	t.Helper()
+	var names []string
+	for i := 0; i < n; i++ {
+		autoTasks++
+		task, err := store.AutoAssign(fmt.Sprintf("Auto task %d", autoTasks), strategy)
+		if err != nil {
+			t.Fatalf("auto-assigning task %d: %v", i, err)
+		}
+		names = append(names, task.Assignee)
+	}
+	return strings.Join(names, " ")
+}
+
+// loads renders the workload report as "name:tasks/capacity" items
+func loads(store *tasks.SafeStore) string {
+	var items []string
+	for _, w := range store.Workloads() {
+		items = append(items, fmt.Sprintf("%s:%d/%d", w.Assignee, w.Tasks, w.Capacity))
+	}
+	return strings.Join(items, " ")
+}
+
+// TestCapacity checks the per-assignee and the overall limits
+func TestCapacity(t *testing.T) {
+	store := tasks.NewSafeStore(0)
+	store.Register("Alice", 2, 0)
+
+	for i := 0; i < 2; i++ {
+		if _, err := store.Assign("Alice", fmt.Sprintf("Alice task %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Assign("alice", "One too many"); !errors.Is(err, tasks.ErrAtCapacity) {
+		t.Errorf("assigning past capacity: got %v, want ErrAtCapacity", err)
+	}
+
+	// Someone who was never registered joins with the default capacity.
+	for i := 0; i < tasks.DefaultCapacity; i++ {
+		if _, err := store.Assign("Bob", fmt.Sprintf("Bob task %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.Assign("Bob", "One too many"); !errors.Is(err, tasks.ErrAtCapacity) {
+		t.Errorf("assigning past the default capacity: got %v, want ErrAtCapacity", err)
+	}
+	want := fmt.Sprintf("Alice:2/2 Bob:%d/%d", tasks.DefaultCapacity, tasks.DefaultCapacity)
+	if got := loads(store); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// Removing a task frees the slot; reassigning needs room at the new assignee.
+	if _, err := store.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reassign(3, "Alice"); err != nil {
+		t.Errorf("reassigning into a free slot: %v", err)
+	}
+	if _, err := store.Reassign(4, "Alice"); !errors.Is(err, tasks.ErrAtCapacity) {
+		t.Errorf("reassigning past capacity: got %v, want ErrAtCapacity", err)
+	}
+
+	// Lowering a capacity keeps the tasks already held but blocks new ones.
+	store.Register("Bob", 1, 0)
+	if _, err := store.Assign("Bob", "Another task"); !errors.Is(err, tasks.ErrAtCapacity) {
+		t.Errorf("assigning to an over-full assignee: got %v, want ErrAtCapacity", err)
+	}
+
+	capped := tasks.NewSafeStore(1)
+	if _, err := capped.Assign("Carol", "Within the overall cap"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := capped.Assign("Dave", "Past the overall cap"); !errors.Is(err, tasks.ErrSystemFull) {
+		t.Errorf("assigning past the overall cap: got %v, want ErrSystemFull", err)
+	}
+}
+
+// TestRoundRobin picks the lowest load, breaks ties in roster order from the
+// last pick and skips assignees at capacity
+func TestRoundRobin(t *testing.T) {
+	store := tasks.NewSafeStore(0)
+	if _, err := store.AutoAssign("Nobody to take it", tasks.RoundRobin); !errors.Is(err, tasks.ErrNoAssignees) {
+		t.Errorf("empty roster: got %v, want ErrNoAssignees", err)
+	}
+
+	store.Register("Alice", 2, 0)
+	store.Register("Bob", 1, 0)
+	store.Register("Carol", 2, 0)
+
+	if got := autoAssign(t, store, 5, tasks.RoundRobin); got != "Alice Bob Carol Alice Carol" {
+		t.Errorf("got %s", got)
+	}
+	if _, err := store.AutoAssign("Nobody has room", tasks.RoundRobin); !errors.Is(err, tasks.ErrAllAtCapacity) {
+		t.Errorf("everyone full: got %v, want ErrAllAtCapacity", err)
+	}
+
+	// Bob holds nothing after the removals, so he goes before Alice.
+	if _, err := store.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Remove(2); err != nil {
+		t.Fatal(err)
+	}
+	if got := autoAssign(t, store, 2, tasks.RoundRobin); got != "Bob Alice" {
+		t.Errorf("after freeing slots: got %s, want Bob Alice", got)
+	}
+}
+
+// TestRoundRobinUnevenLoad evens out loads left by manual assignments
+func TestRoundRobinUnevenLoad(t *testing.T) {
+	store := tasks.NewSafeStore(0)
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		store.Register(name, 5, 0)
+	}
+	for i, name := range []string{"Alice", "Alice", "Alice", "Bob"} {
+		if _, err := store.Assign(name, fmt.Sprintf("Manual task %d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := autoAssign(t, store, 5, tasks.RoundRobin); got != "Carol Bob Carol Bob Carol" {
+		t.Errorf("got %s", got)
+	}
+	if got := loads(store); got != "Alice:3/5 Bob:3/5 Carol:3/5" {
+		t.Errorf("after 5 tasks: got %s", got)
+	}
+}
+
+// TestAutoAssignFallsBack passes a task on when the first pick already holds it
+func TestAutoAssignFallsBack(t *testing.T) {
+	for _, strategy := range []tasks.Strategy{tasks.RoundRobin, tasks.Weighted} {
+		store := tasks.NewSafeStore(0)
+		store.Register("Alice", 3, 0)
+		store.Register("Bob", 3, 0)
+		store.Assign("Alice", "Fix the build")
+		store.Assign("Bob", "Write the report")
+		store.Assign("Bob", "Plan the sprint")
+
+		task, err := store.AutoAssign("Fix the build", strategy)
+		if err != nil || task.Assignee != "Bob" {
+			t.Errorf("%s: got %+v, %v, want the task with Bob", strategy, task, err)
+		}
+		_, err = store.AutoAssign("fix the  build", strategy)
+		if !errors.Is(err, tasks.ErrNoEligible) {
+			t.Errorf("%s: everyone holds it: got %v, want ErrNoEligible", strategy, err)
+		}
+		failedRule(t, string(strategy)+": everyone holds it", err, "details", "duplicate")
+		if store.Len() != 4 {
+			t.Errorf("%s: got %d tasks, want 4", strategy, store.Len())
+		}
+	}
+}
+
+// TestWeighted shares tasks out in proportion to weight, with ties going to
+// the assignee registered first, until someone reaches their capacity
+func TestWeighted(t *testing.T) {
+	store := tasks.NewSafeStore(0)
+	store.Register("Alice", 3, 1)
+	store.Register("Bob", 6, 3)
+
+	if got := autoAssign(t, store, 8, tasks.Weighted); got != "Alice Bob Bob Bob Alice Bob Bob Bob" {
+		t.Errorf("got %s", got)
+	}
+	if got := loads(store); got != "Alice:2/3 Bob:6/6" {
+		t.Errorf("after 8 tasks: got %s", got)
+	}
+
+	// Bob is full, so Alice takes the next task despite the higher relative load.
+	if got := autoAssign(t, store, 1, tasks.Weighted); got != "Alice" {
+		t.Errorf("with Bob full: got %s, want Alice", got)
+	}
+	if _, err := store.AutoAssign("Nobody has room", tasks.Weighted); !errors.Is(err, tasks.ErrAllAtCapacity) {
+		t.Errorf("everyone full: got %v, want ErrAllAtCapacity", err)
+	}
+
+	if _, err := store.AutoAssign("Pick at random", "random"); !errors.Is(err, tasks.ErrUnknownStrategy) {
+		t.Errorf("unknown strategy: got %v, want ErrUnknownStrategy", err)
+	}
+}
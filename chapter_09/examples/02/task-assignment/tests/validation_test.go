@@ -0,0 +1,116 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"task-assignment/tasks"
+)
+
+// failedRule checks that err is a validation error for field and rule
+func failedRule(t *testing.T, what string, err error, field, rule string) {
+	t.Helper()
+	var invalid *tasks.ValidationError
+	if !errors.As(err, &invalid) || !invalid.Has(field, rule) {
+		t.Errorf("%s: got %v, want a %s %s failure", what, err, field, rule)
+	}
+}
+
+// TestRulesApplyToEveryPath checks that assigning, auto-assigning and
+// reassigning all enforce the duplicate and roster rules
+func TestRulesApplyToEveryPath(t *testing.T) {
+	store := tasks.NewSafeStore(0)
+	store.Register("Alice", 5, 0)
+
+	if _, err := store.Assign("Alice", "Review the budget"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := store.Assign("alice", "review  the BUDGET")
+	failedRule(t, "assigning a duplicate", err, "details", "duplicate")
+	_, err = store.AutoAssign("Review the budget", tasks.RoundRobin)
+	failedRule(t, "auto-assigning a duplicate", err, "details", "duplicate")
+	if !errors.Is(err, tasks.ErrNoEligible) {
+		t.Errorf("auto-assigning a duplicate: got %v, want ErrNoEligible", err)
+	}
+
+	// Bob holds nothing yet, so he comes before Alice.
+	store.Register("Bob", 5, 0)
+	if task, err := store.AutoAssign("Plan the offsite", tasks.RoundRobin); err != nil || task.Assignee != "Bob" {
+		t.Errorf("next round-robin task: got %+v, %v, want Bob", task, err)
+	}
+
+	bobs, err := store.Assign("Bob", "Review the budget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.Reassign(bobs.ID, "Alice")
+	failedRule(t, "reassigning onto a duplicate", err, "details", "duplicate")
+	_, err = store.Reassign(bobs.ID, "B0b")
+	failedRule(t, "reassigning to an invalid name", err, "assignee", "characters")
+
+	rules := tasks.DefaultRules
+	rules.KnownAssignees = []string{"Alice", "Bob"}
+	store.SetRules(rules)
+	_, err = store.Assign("Mallory", "Read the mail")
+	failedRule(t, "assigning off the roster", err, "assignee", "known_assignee")
+	_, err = store.Reassign(bobs.ID, "Mallory")
+	failedRule(t, "reassigning off the roster", err, "assignee", "known_assignee")
+
+	// Duplicates are allowed once the rule is off.
+	rules.RejectDuplicates = false
+	store.SetRules(rules)
+	if _, err := store.Reassign(bobs.ID, "Alice"); err != nil {
+		t.Errorf("reassigning a duplicate with the rule off: %v", err)
+	}
+}
+
+// writeRoster writes a roster file and returns its path
+func writeRoster(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "roster.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadRoster checks that a roster replaces the previous one and that
+// an empty roster is rejected rather than lifting the limit
+func TestLoadRoster(t *testing.T) {
+	store := tasks.NewSafeStore(0)
+
+	names, err := store.LoadRoster(writeRoster(t, "# name,capacity,weight", "Alice,2", "Bob,3,2"))
+	if err != nil || strings.Join(names, " ") != "Alice Bob" {
+		t.Fatalf("got %v, %v", names, err)
+	}
+	if got := loads(store); got != "Alice:0/2 Bob:0/3" {
+		t.Errorf("got %s", got)
+	}
+
+	// A second roster may list people the first one did not.
+	names, err = store.LoadRoster(writeRoster(t, "Carol,4"))
+	if err != nil || strings.Join(names, " ") != "Carol" {
+		t.Fatalf("replacing the roster: got %v, %v", names, err)
+	}
+	if known := store.CurrentRules().KnownAssignees; strings.Join(known, " ") != "Carol" {
+		t.Errorf("known assignees: got %v, want [Carol]", known)
+	}
+
+	for name, lines := range map[string][]string{
+		"empty":        nil,
+		"comments":     {"# nobody yet", ""},
+		"bad name":     {"Alice", "D4ve"},
+		"bad capacity": {"Alice,lots"},
+		"extra field":  {"Alice,1,1,1"},
+	} {
+		if _, err := store.LoadRoster(writeRoster(t, lines...)); err == nil {
+			t.Errorf("%s roster: accepted", name)
+		}
+	}
+	if known := store.CurrentRules().KnownAssignees; strings.Join(known, " ") != "Carol" {
+		t.Errorf("a rejected roster changed the known assignees to %v", known)
+	}
+}
@@ -2,19 +2,13 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitDB initializes the SQLite database and creates the table if it doesn't exist.
-func InitDB(dbPath string) *sql.DB {
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	createTable := `
+const createImageTable = `
 	CREATE TABLE IF NOT EXISTS image (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT NOT NULL,
@@ -28,9 +22,24 @@ func InitDB(dbPath string) *sql.DB {
 		status_url TEXT
 	)`
 
-	if _, err := db.Exec(createTable); err != nil {
-		log.Fatal(err)
+// Open opens the SQLite database at path and creates the table if it doesn't exist.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
 	}
+	if _, err := db.Exec(createImageTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating image table in %s: %w", path, err)
+	}
+	return db, nil
+}
 
+// InitDB initializes the SQLite database and creates the table if it doesn't exist.
+func InitDB(dbPath string) *sql.DB {
+	db, err := Open(dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
 	return db
 }
@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound      = errors.New("image not found")
+	ErrInvalidStatus = errors.New("invalid status, choose active, suspended or revoked")
+	ErrMissingField  = errors.New("missing required field")
+)
+
+// Statuses allowed by the image table
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusRevoked   = "revoked"
+)
+
+// Image is one row of the image table
+type Image struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	Contents        string `json:"contents"`
+	SHA256          string `json:"sha256"`
+	HMAC            string `json:"hmac"`
+	Team            string `json:"team"`
+	TeamOwner       string `json:"team_owner"`
+	Status          string `json:"status"`
+	StatusSignature string `json:"status_signature"`
+	StatusURL       string `json:"status_url"` // empty when NULL
+}
+
+// ImageInput holds the caller-supplied fields of an image; the hashes and
+// signature are computed by the repository
+type ImageInput struct {
+	Name      string
+	Contents  string
+	Team      string
+	TeamOwner string
+	Status    string // defaults to active
+	StatusURL string
+}
+
+// validate checks the required fields and fills in the default status
+func (in *ImageInput) validate() error {
+	for field, value := range map[string]string{"name": in.Name, "team": in.Team, "team_owner": in.TeamOwner} {
+		if value == "" {
+			return fmt.Errorf("%w: %s", ErrMissingField, field)
+		}
+	}
+	if in.Status == "" {
+		in.Status = StatusActive
+	}
+	switch in.Status {
+	case StatusActive, StatusSuspended, StatusRevoked:
+		return nil
+	}
+	return fmt.Errorf("%w (got %q)", ErrInvalidStatus, in.Status)
+}
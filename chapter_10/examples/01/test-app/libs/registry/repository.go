@@ -0,0 +1,189 @@
+package registry
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"test-app/utils"
+)
+
+// imageColumns lists the columns in the order scanImage reads them
+const imageColumns = "id, name, contents, sha256, hmac, team, team_owner, status, status_signature, status_url"
+
+// ImageRepository reads and writes the image table
+type ImageRepository struct {
+	db        *sql.DB
+	secretKey string
+}
+
+// NewImageRepository returns a repository that signs images with secretKey
+func NewImageRepository(db *sql.DB, secretKey string) *ImageRepository {
+	return &ImageRepository{db: db, secretKey: secretKey}
+}
+
+// sign fills in the hashes and status signature of img from its other fields
+func (r *ImageRepository) sign(img *Image) {
+	img.SHA256 = utils.GenerateHash(img.Contents)
+	img.HMAC = utils.GenerateHMAC(r.secretKey, img.Contents)
+	dataToSign := img.Name + img.Contents + img.SHA256 + img.HMAC + img.Team + img.TeamOwner + img.Status + img.StatusURL
+	img.StatusSignature = utils.GenerateHMAC(r.secretKey, dataToSign)
+}
+
+// Create signs and stores a new image
+func (r *ImageRepository) Create(in ImageInput) (Image, error) {
+	if err := in.validate(); err != nil {
+		return Image{}, err
+	}
+	img := Image{
+		Name:      in.Name,
+		Contents:  in.Contents,
+		Team:      in.Team,
+		TeamOwner: in.TeamOwner,
+		Status:    in.Status,
+		StatusURL: in.StatusURL,
+	}
+	r.sign(&img)
+
+	result, err := r.db.Exec(`
+		INSERT INTO image (name, contents, sha256, hmac, team, team_owner, status, status_signature, status_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		img.Name, img.Contents, img.SHA256, img.HMAC, img.Team, img.TeamOwner, img.Status, img.StatusSignature, nullString(img.StatusURL))
+	if err != nil {
+		return Image{}, fmt.Errorf("creating image %q: %w", img.Name, err)
+	}
+	if img.ID, err = result.LastInsertId(); err != nil {
+		return Image{}, fmt.Errorf("creating image %q: %w", img.Name, err)
+	}
+	return img, nil
+}
+
+// Get returns the image with the given name, or ErrNotFound
+func (r *ImageRepository) Get(name string) (Image, error) {
+	row := r.db.QueryRow("SELECT "+imageColumns+" FROM image WHERE name = ? ORDER BY id DESC LIMIT 1", name)
+	img, err := scanImage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Image{}, fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("reading image %q: %w", name, err)
+	}
+	return img, nil
+}
+
+// Update replaces the contents, team, owner and status of an image and re-signs it
+func (r *ImageRepository) Update(name string, in ImageInput) (Image, error) {
+	in.Name = name
+	if err := in.validate(); err != nil {
+		return Image{}, err
+	}
+	img := Image{
+		Name:      name,
+		Contents:  in.Contents,
+		Team:      in.Team,
+		TeamOwner: in.TeamOwner,
+		Status:    in.Status,
+		StatusURL: in.StatusURL,
+	}
+	r.sign(&img)
+
+	result, err := r.db.Exec(`
+		UPDATE image
+		SET contents = ?, sha256 = ?, hmac = ?, team = ?, team_owner = ?, status = ?, status_signature = ?, status_url = ?
+		WHERE name = ?`,
+		img.Contents, img.SHA256, img.HMAC, img.Team, img.TeamOwner, img.Status, img.StatusSignature, nullString(img.StatusURL), name)
+	if err != nil {
+		return Image{}, fmt.Errorf("updating image %q: %w", name, err)
+	}
+	if err := expectRows(result, name); err != nil {
+		return Image{}, err
+	}
+	return r.Get(name)
+}
+
+// Delete removes the image with the given name, or returns ErrNotFound
+func (r *ImageRepository) Delete(name string) error {
+	result, err := r.db.Exec("DELETE FROM image WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("deleting image %q: %w", name, err)
+	}
+	return expectRows(result, name)
+}
+
+// List returns every image, oldest first
+func (r *ImageRepository) List() ([]Image, error) {
+	rows, err := r.db.Query("SELECT " + imageColumns + " FROM image ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("listing images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []Image
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("listing images: %w", err)
+		}
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing images: %w", err)
+	}
+	return images, nil
+}
+
+// ExportCSV writes every image as CSV with a header row; NULLs are written as empty fields
+func (r *ImageRepository) ExportCSV(w io.Writer) error {
+	images, err := r.List()
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "name", "contents", "sha256", "hmac", "team", "team_owner", "status", "status_signature", "status_url"})
+	for _, img := range images {
+		writer.Write([]string{
+			strconv.FormatInt(img.ID, 10), img.Name, img.Contents, img.SHA256, img.HMAC,
+			img.Team, img.TeamOwner, img.Status, img.StatusSignature, img.StatusURL,
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("exporting images: %w", err)
+	}
+	return nil
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanImage(row scanner) (Image, error) {
+	var img Image
+	var signature, statusURL sql.NullString
+	err := row.Scan(&img.ID, &img.Name, &img.Contents, &img.SHA256, &img.HMAC,
+		&img.Team, &img.TeamOwner, &img.Status, &signature, &statusURL)
+	img.StatusSignature = signature.String
+	img.StatusURL = statusURL.String
+	return img, err
+}
+
+// expectRows turns an UPDATE or DELETE that touched nothing into ErrNotFound
+func expectRows(result sql.Result, name string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("image %q: %w", name, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
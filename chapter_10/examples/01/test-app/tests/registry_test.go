@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"test-app/libs/db"
+	"test-app/libs/registry"
+	"test-app/utils"
+)
+
+const secretKey = "test-secret"
+
+// newRepository opens a fresh registry in a temporary SQLite file
+func newRepository(t *testing.T) *registry.ImageRepository {
+	t.Helper()
+	conn, err := db.Open(filepath.Join(t.TempDir(), "images.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return registry.NewImageRepository(conn, secretKey)
+}
+
+func exampleInput() registry.ImageInput {
+	return registry.ImageInput{Name: "example_image", Contents: "example_data", Team: "team_a", TeamOwner: "owner_a"}
+}
+
+// TestCreateAndGet checks that a created image reads back signed and complete
+func TestCreateAndGet(t *testing.T) {
+	images := newRepository(t)
+
+	created, err := images.Create(exampleInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Status != registry.StatusActive {
+		t.Errorf("unexpected image %+v", created)
+	}
+	if created.SHA256 != utils.GenerateHash("example_data") || created.HMAC != utils.GenerateHMAC(secretKey, "example_data") {
+		t.Errorf("hashes not computed: %+v", created)
+	}
+
+	got, err := images.Get("example_image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != created {
+		t.Errorf("Get returned %+v, want %+v", got, created)
+	}
+}
+
+// TestNotFound checks that missing images are reported with ErrNotFound
+func TestNotFound(t *testing.T) {
+	images := newRepository(t)
+
+	if _, err := images.Get("missing"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Get: got %v, want ErrNotFound", err)
+	}
+	if _, err := images.Update("missing", exampleInput()); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Update: got %v, want ErrNotFound", err)
+	}
+	if err := images.Delete("missing"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+// TestUpdateAndDelete checks that an update re-signs the image and delete removes it
+func TestUpdateAndDelete(t *testing.T) {
+	images := newRepository(t)
+	created, err := images.Create(exampleInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := exampleInput()
+	in.Contents = "new_data"
+	in.Status = registry.StatusSuspended
+	in.StatusURL = "https://example.com/cve-details"
+	updated, err := images.Update("example_image", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.SHA256 == created.SHA256 || updated.StatusSignature == created.StatusSignature || updated.StatusURL != in.StatusURL {
+		t.Errorf("update not applied: %+v", updated)
+	}
+
+	if err := images.Delete("example_image"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := images.Get("example_image"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Get after Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+// TestInvalidInput checks the required fields and the status values
+func TestInvalidInput(t *testing.T) {
+	images := newRepository(t)
+
+	in := exampleInput()
+	in.Team = ""
+	if _, err := images.Create(in); !errors.Is(err, registry.ErrMissingField) {
+		t.Errorf("missing team: got %v, want ErrMissingField", err)
+	}
+	in = exampleInput()
+	in.Status = "deleted"
+	if _, err := images.Create(in); !errors.Is(err, registry.ErrInvalidStatus) {
+		t.Errorf("bad status: got %v, want ErrInvalidStatus", err)
+	}
+}
+
+// TestExportCSV checks that a NULL status_url is exported as an empty field
+func TestExportCSV(t *testing.T) {
+	images := newRepository(t)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := images.ExportCSV(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want header and one row:\n%s", len(lines), out.String())
+	}
+	if strings.Contains(lines[1], "<nil>") || !strings.HasSuffix(lines[1], ",") {
+		t.Errorf("status_url not exported as empty: %s", lines[1])
+	}
+}
@@ -0,0 +1,604 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"test-app/libs/blobstore"
+	"test-app/libs/db"
+	"test-app/libs/registry"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK       = 0 // command succeeded
+	ExitError    = 1 // command failed
+	ExitUsage    = 2 // bad subcommand, flag or argument
+	ExitTampered = 3 // verify found rows that do not match their hashes or signatures
+)
+
+// Config tells the commands where the registry lives and how it is signed
+type Config struct {
+	DBPath  string
+	BlobDir string            // root of the blob store holding the image contents
+	Keys    *registry.Keyring // nil loads the keyring from the environment, see registry.KeyringFromEnv
+}
+
+// errTampered makes verify exit with ExitTampered after printing its report
+var errTampered = errors.New("integrity check failed")
+
+const usage = `Usage: test-app [command] [flags]
+
+Without a command the create/read/export example runs.
+The database is images.db unless IMAGE_DB is set, and image contents are kept
+in the blob store under ./blobs unless IMAGE_BLOBS is set. Signing keys come from the
+keyring file in IMAGE_KEYRING, or from IMAGE_KEYS="id=secret,..." with
+IMAGE_ACTIVE_KEY naming the key used for new signatures.
+
+Commands:
+  migrate [--dry-run]      apply pending schema migrations (or list them)
+  verify [--json]          recompute every row's hashes and signatures; exits 3 if any fail
+  resign [--dry-run]       re-sign rows still using an older signature encoding
+  rotate --to KEY_ID       re-sign every row with another key from the keyring
+  suspend|reinstate|revoke NAME --actor WHO --reason WHY [--url URL]
+                           change an image's status (revoked is final)
+  audit [NAME] [--json]    show the status change log
+  export [--format csv|jsonl|columnar] [--team T] [--owner O] [--status S]
+         [--columns a,b,...] [--out FILE] [--manifest FILE]
+                           stream the matching rows to FILE (default images_export.FORMAT)
+                           and write a manifest with the row count and SHA-256
+  import PATH [--on-duplicate skip|update] [--batch N] [--team T --owner O]
+                           load a CSV or JSON Lines export, or every file under a directory
+  history NAME [--json]    list every version of an image
+  cat NAME [--version N]   stream an image's contents (default the latest version),
+                           checked against its digest
+  gc [--dry-run] [--min-age 1h]
+                           remove blobs no image refers to (and older than --min-age)
+  help                     show this message
+`
+
+// Run executes one subcommand and returns the process exit code
+func Run(cfg Config, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return ExitUsage
+	}
+
+	var err error
+	switch args[0] {
+	case "migrate":
+		err = runMigrate(cfg, args[1:], stdout)
+	case "verify":
+		err = runVerify(cfg, args[1:], stdout)
+	case "resign":
+		err = runResign(cfg, args[1:], stdout)
+	case "rotate":
+		err = runRotate(cfg, args[1:], stdout)
+	case "suspend", "reinstate", "revoke":
+		err = runTransition(cfg, args[0], args[1:], stdout)
+	case "audit":
+		err = runAudit(cfg, args[1:], stdout)
+	case "export":
+		err = runExport(cfg, args[1:], stdout, stderr)
+	case "import":
+		err = runImport(cfg, args[1:], stdout)
+	case "history":
+		err = runHistory(cfg, args[1:], stdout)
+	case "cat":
+		err = runCat(cfg, args[1:], stdout)
+	case "gc":
+		err = runGC(cfg, args[1:], stdout)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return ExitOK
+	default:
+		err = usageError{fmt.Sprintf("unknown command %q", args[0])}
+	}
+
+	if err == nil {
+		return ExitOK
+	}
+	fmt.Fprintln(stderr, "Error:", err)
+	var uerr usageError
+	switch {
+	case errors.As(err, &uerr):
+		return ExitUsage
+	case errors.Is(err, errTampered):
+		return ExitTampered
+	}
+	return ExitError
+}
+
+// usageError marks mistakes in how the command was invoked
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+// parseFlags parses args into a flag set that reports errors instead of exiting
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return usageError{fmt.Sprintf("%s: see 'test-app help'", fs.Name())}
+		}
+		return usageError{fmt.Sprintf("%s: %v", fs.Name(), err)}
+	}
+	if fs.NArg() > 0 {
+		return usageError{fmt.Sprintf("%s: unexpected argument %q", fs.Name(), fs.Arg(0))}
+	}
+	return nil
+}
+
+// openRepository loads the keys and opens the registry, migrating it first
+func openRepository(cfg Config) (*registry.ImageRepository, func() error, error) {
+	keys := cfg.Keys
+	if keys == nil {
+		var err error
+		if keys, err = registry.KeyringFromEnv(); err != nil {
+			return nil, nil, err
+		}
+	}
+	blobs, err := blobstore.New(cfg.BlobDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := db.Open(cfg.DBPath, blobs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return registry.NewImageRepository(conn, keys, blobs), conn.Close, nil
+}
+
+func runMigrate(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "list pending migrations without applying them")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// A dry run applies nothing, so it must not create the blob store either;
+	// only the step moving contents into it ever writes to blobs.
+	var blobs db.BlobWriter
+	if !*dryRun {
+		store, err := blobstore.New(cfg.BlobDir)
+		if err != nil {
+			return err
+		}
+		blobs = store
+	}
+	conn, err := db.Connect(cfg.DBPath)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrations, err := db.Migrate(conn, db.Migrations(blobs), db.MigrateOptions{DryRun: *dryRun})
+	verb := "Applied"
+	if *dryRun {
+		verb = "Pending"
+	}
+	for _, m := range migrations {
+		fmt.Fprintf(stdout, "%s migration %d: %s\n", verb, m.Version, m.Description)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, err := db.SchemaVersion(conn)
+	if err != nil {
+		return err
+	}
+	switch {
+	case len(migrations) == 0:
+		fmt.Fprintf(stdout, "Schema is up to date at version %d.\n", version)
+	case *dryRun:
+		fmt.Fprintf(stdout, "Schema is at version %d; run without --dry-run to apply.\n", version)
+	default:
+		fmt.Fprintf(stdout, "Schema is at version %d.\n", version)
+	}
+	return nil
+}
+
+func runVerify(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := images.VerifyAll()
+	if err != nil {
+		return err
+	}
+	audit, err := images.VerifyAudit()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		if err := writeJSON(stdout, map[string]any{"images": report, "audit": audit}); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range report.Tampered {
+			fmt.Fprintf(stdout, "TAMPERED image %d (%s): %s\n", result.ID, result.Name, strings.Join(result.Failed, ", "))
+		}
+		fmt.Fprintf(stdout, "Checked %d image(s), %d failed.\n", report.Checked, len(report.Tampered))
+		if report.Legacy > 0 {
+			fmt.Fprintf(stdout, "%d image(s) use an older signature encoding; run 'test-app resign'.\n", report.Legacy)
+		}
+		for _, b := range audit.Broken {
+			fmt.Fprintf(stdout, "BROKEN audit entry %d: %s\n", b.Seq, b.Reason)
+		}
+		fmt.Fprintf(stdout, "Checked %d audit entries, %d broken, head %s.\n", audit.Checked, len(audit.Broken), audit.Head)
+	}
+
+	if len(report.Tampered) > 0 || len(audit.Broken) > 0 {
+		return fmt.Errorf("%w: %d of %d image(s), %d of %d audit entries",
+			errTampered, len(report.Tampered), report.Checked, len(audit.Broken), audit.Checked)
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func runResign(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("resign", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "report what would be re-signed without writing")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := images.Resign(*dryRun)
+	if err != nil {
+		return err
+	}
+	for _, result := range report.Skipped {
+		fmt.Fprintf(stdout, "SKIPPED image %d (%s): fails verification on %s\n", result.ID, result.Name, strings.Join(result.Failed, ", "))
+	}
+	verb := "Re-signed"
+	if *dryRun {
+		verb = "Would re-sign"
+	}
+	fmt.Fprintf(stdout, "%s %d image(s) with signature version %d.\n", verb, report.Resigned, registry.CurrentSignatureVersion)
+	if len(report.Skipped) > 0 {
+		return fmt.Errorf("%w: %d image(s) left on their old signature", errTampered, len(report.Skipped))
+	}
+	return nil
+}
+
+func runRotate(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("rotate", flag.ContinueOnError)
+	to := fs.String("to", "", "key ID to re-sign every row with")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *to == "" {
+		return usageError{"rotate: --to KEY_ID is required"}
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := images.Rotate(*to)
+	if errors.Is(err, registry.ErrVerificationFailed) {
+		return fmt.Errorf("%w; nothing was rotated", err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Re-signed %d image(s) and %d audit entries with key %q.\n", report.Rotated, report.AuditEntries, report.KeyID)
+	return nil
+}
+
+func runTransition(cfg Config, command string, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	actor := fs.String("actor", "", "who makes the change")
+	reason := fs.String("reason", "", "why the status changes")
+	statusURL := fs.String("url", "", "link to the advisory or ticket behind the change")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usageError{fmt.Sprintf("%s: image name is required", command)}
+	}
+	name := args[0]
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	change := map[string]func(string, registry.Transition) (registry.Image, error){
+		"suspend":   images.Suspend,
+		"reinstate": images.Reinstate,
+		"revoke":    images.Revoke,
+	}[command]
+	img, err := change(name, registry.Transition{Actor: *actor, Reason: *reason, StatusURL: *statusURL})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Image %s is now %s.\n", img.Name, img.Status)
+	return nil
+}
+
+func runAudit(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the entries as JSON")
+	name := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	entries, err := images.AuditLog(name)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(stdout, entries)
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(stdout, "No status changes recorded.")
+	}
+	for _, e := range entries {
+		fmt.Fprintf(stdout, "#%d %s %s: %s -> %s by %s (%s)", e.Seq, e.At.Format("2006-01-02 15:04:05"), e.ImageName, e.FromStatus, e.ToStatus, e.Actor, e.Reason)
+		if e.StatusURL != "" {
+			fmt.Fprintf(stdout, " %s", e.StatusURL)
+		}
+		fmt.Fprintln(stdout)
+	}
+	return nil
+}
+
+func runExport(cfg Config, args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", string(registry.FormatCSV), "csv, jsonl or columnar")
+	team := fs.String("team", "", "only rows of this team")
+	owner := fs.String("owner", "", "only rows of this team owner")
+	status := fs.String("status", "", "only rows with this status")
+	columns := fs.String("columns", "", "comma separated columns to export (default all)")
+	out := fs.String("out", "", "file to write, - for stdout (default images_export.FORMAT)")
+	manifestPath := fs.String("manifest", "", "where to write the manifest (default FILE.manifest.json, stderr with --out -)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts := registry.ExportOptions{
+		Format: registry.ExportFormat(*format),
+		Filter: registry.ExportFilter{Team: *team, TeamOwner: *owner, Status: *status},
+	}
+	if *columns != "" {
+		for _, name := range strings.Split(*columns, ",") {
+			opts.Columns = append(opts.Columns, strings.TrimSpace(name))
+		}
+	}
+	if *out == "" {
+		*out = "images_export." + *format
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	var manifest registry.ExportManifest
+	if *out == "-" {
+		if manifest, err = images.Export(stdout, opts); err != nil {
+			return err
+		}
+	} else {
+		// Write to a temporary file first so a failed export never leaves half a file behind.
+		tmp, err := os.CreateTemp(filepath.Dir(*out), filepath.Base(*out)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		manifest, err = images.Export(tmp, opts)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp.Name(), *out); err != nil {
+			return err
+		}
+		if *manifestPath == "" {
+			*manifestPath = *out + ".manifest.json"
+		}
+	}
+
+	if *manifestPath == "" {
+		return writeJSON(stderr, manifest)
+	}
+	file, err := os.Create(*manifestPath)
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(file, manifest); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if *out != "-" {
+		fmt.Fprintf(stdout, "Exported %d image(s) to %s (sha256 %s), manifest in %s.\n", manifest.Rows, *out, manifest.SHA256, *manifestPath)
+	}
+	return nil
+}
+
+func runImport(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	onDuplicate := fs.String("on-duplicate", string(registry.DuplicateSkip), "skip or update images whose name exists")
+	batch := fs.Int("batch", 0, "records per transaction (default 500)")
+	team := fs.String("team", "", "team for files imported from a directory")
+	owner := fs.String("owner", "", "team owner for files imported from a directory")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usageError{"import: a file or directory is required"}
+	}
+	path := args[0]
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := images.ImportFile(path, registry.ImportOptions{
+		OnDuplicate: registry.DuplicatePolicy(*onDuplicate),
+		BatchSize:   *batch,
+		Team:        *team,
+		TeamOwner:   *owner,
+	})
+	for _, f := range report.Failed {
+		fmt.Fprintf(stdout, "FAILED %s (%s): %s\n", f.Source, f.Name, f.Err)
+	}
+	fmt.Fprintf(stdout, "Inserted %d, updated %d, skipped %d, failed %d.\n", report.Inserted, report.Updated, report.Skipped, len(report.Failed))
+	if err != nil {
+		return err
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("%d record(s) could not be imported", len(report.Failed))
+	}
+	return nil
+}
+
+func runHistory(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the versions as JSON")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usageError{"history: image name is required"}
+	}
+	name := args[0]
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	history, err := images.History(name)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(stdout, history)
+	}
+	for _, img := range history {
+		fmt.Fprintf(stdout, "v%d  %s  %d bytes  %s/%s  %s\n", img.Version, img.SHA256[:12], img.Size, img.Team, img.TeamOwner, img.Status)
+	}
+	return nil
+}
+
+func runCat(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	version := fs.Int("version", 0, "version to print (default the latest)")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return usageError{"cat: image name is required"}
+	}
+	name := args[0]
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	if *version < 0 {
+		return usageError{"cat: --version must be positive"}
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	open := images.OpenContents
+	if *version > 0 {
+		open = func(name string) (io.ReadCloser, registry.Image, error) {
+			return images.OpenVersion(name, *version)
+		}
+	}
+	blob, _, err := open(name)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+	if _, err := io.Copy(stdout, blob); err != nil {
+		if errors.Is(err, blobstore.ErrDigestMismatch) {
+			return fmt.Errorf("%w: %v", errTampered, err)
+		}
+		return err
+	}
+	return nil
+}
+
+func runGC(cfg Config, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "list unreferenced blobs without removing them")
+	minAge := fs.Duration("min-age", time.Hour, "keep blobs written more recently than this")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	images, closeDB, err := openRepository(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	report, err := images.CollectGarbage(blobstore.GCOptions{DryRun: *dryRun, MinAge: *minAge})
+	if err != nil {
+		return err
+	}
+	verb := "Removed"
+	if *dryRun {
+		verb = "Would remove"
+	}
+	for _, digest := range report.Removed {
+		fmt.Fprintf(stdout, "%s blob %s\n", verb, digest)
+	}
+	fmt.Fprintf(stdout, "%s %d blob(s), %d bytes; kept %d.\n", verb, len(report.Removed), report.Bytes, report.Kept)
+	return nil
+}
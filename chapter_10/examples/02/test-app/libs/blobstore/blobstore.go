@@ -0,0 +1,200 @@
+package blobstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound       = errors.New("blob not found")
+	ErrInvalidDigest  = errors.New("invalid digest, want 64 lowercase hex characters")
+	ErrDigestMismatch = errors.New("blob does not match its digest")
+)
+
+// Store keeps blobs on disk under the hex SHA-256 of their contents,
+// sharded by the first two bytes: ROOT/sha256/ab/cd/abcd...
+// Writing the same contents twice stores them once.
+type Store struct {
+	root string
+}
+
+// New returns a store rooted at dir, creating it if needed
+func New(dir string) (*Store, error) {
+	for _, sub := range []string{"sha256", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("creating blob store: %w", err)
+		}
+	}
+	return &Store{root: dir}, nil
+}
+
+// Path returns where the blob with the given digest lives
+func (s *Store) Path(digest string) (string, error) {
+	if !validDigest(digest) {
+		return "", fmt.Errorf("%w (got %q)", ErrInvalidDigest, digest)
+	}
+	return filepath.Join(s.root, "sha256", digest[0:2], digest[2:4], digest), nil
+}
+
+// Put streams r into the store and returns its digest and size. The data is
+// written to a temporary file and renamed into place, so a blob is either
+// complete or absent.
+func (s *Store) Put(r io.Reader) (digest string, size int64, err error) {
+	tmp, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "put-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("storing blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("storing blob: %w", err)
+	}
+
+	digest = hex.EncodeToString(h.Sum(nil))
+	path, _ := s.Path(digest)
+	if _, err := os.Stat(path); err == nil {
+		// Already stored; refresh the time so GC's grace period starts again.
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return digest, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, fmt.Errorf("storing blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("storing blob: %w", err)
+	}
+	return digest, size, nil
+}
+
+// Open streams the blob with the given digest. The reader hashes what it
+// returns and fails with ErrDigestMismatch instead of io.EOF if the file no
+// longer matches its digest.
+func (s *Store) Open(digest string) (io.ReadCloser, error) {
+	path, err := s.Path(digest)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", digest, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{file: file, hash: sha256.New(), digest: digest}, nil
+}
+
+// Stat returns the size of the stored blob, or ErrNotFound
+func (s *Store) Stat(digest string) (int64, error) {
+	path, err := s.Path(digest)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("%s: %w", digest, ErrNotFound)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+type verifyingReader struct {
+	file   *os.File
+	hash   hash.Hash
+	digest string
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.file.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(v.hash.Sum(nil)) != v.digest {
+		return n, fmt.Errorf("%s: %w", v.digest, ErrDigestMismatch)
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	return v.file.Close()
+}
+
+// GCOptions controls GC
+type GCOptions struct {
+	DryRun bool // report what would be removed without removing it
+	// MinAge spares blobs written more recently than this, so a blob stored
+	// just before the row that references it is committed survives.
+	MinAge time.Duration
+}
+
+// GCReport is the outcome of GC
+type GCReport struct {
+	Kept    int      `json:"kept"`
+	Removed []string `json:"removed"` // digests
+	Bytes   int64    `json:"bytes"`   // size of the removed blobs
+}
+
+// GC removes every blob whose digest is not in referenced, along with
+// leftover temporary files, unless they are younger than opts.MinAge
+func (s *Store) GC(referenced map[string]bool, opts GCOptions) (GCReport, error) {
+	report := GCReport{Removed: []string{}}
+	cutoff := time.Now().Add(-opts.MinAge)
+
+	err := filepath.WalkDir(s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		isTemp := filepath.Dir(path) == filepath.Join(s.root, "tmp")
+		if !isTemp && (!validDigest(name) || referenced[name]) {
+			report.Kept++
+			return nil
+		}
+		if info.ModTime().After(cutoff) {
+			report.Kept++
+			return nil
+		}
+		if !isTemp {
+			report.Removed = append(report.Removed, name)
+			report.Bytes += info.Size()
+		}
+		if opts.DryRun {
+			return nil
+		}
+		return os.Remove(path)
+	})
+	if err != nil {
+		return report, fmt.Errorf("collecting blobs: %w", err)
+	}
+	return report, nil
+}
+
+func validDigest(digest string) bool {
+	if len(digest) != sha256.Size*2 || strings.ToLower(digest) != digest {
+		return false
+	}
+	_, err := hex.DecodeString(digest)
+	return err == nil
+}
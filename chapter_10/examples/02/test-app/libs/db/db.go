@@ -2,19 +2,25 @@ package db
 
 import (
 	"database/sql"
+	"fmt"
+	"io"
 	"log"
+	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-// InitDB initializes the database and creates the required table if not exists.
-func InitDB() *sql.DB {
-	db, err := sql.Open("sqlite3", "images.db")
-	if err != nil {
-		log.Fatal(err)
-	}
+// BlobWriter stores contents and returns their hex SHA-256 and size; *blobstore.Store is one
+type BlobWriter interface {
+	Put(r io.Reader) (digest string, size int64, err error)
+}
 
-	createTable := `
+// Migrations builds the registry schema; append new steps, never edit applied ones.
+// blobs receives the image contents moved out of the table by step 5.
+func Migrations(blobs BlobWriter) []Migration {
+	return []Migration{
+		// IF NOT EXISTS adopts databases created before schema_version existed.
+		{1, "create image table", SQL(`
 	CREATE TABLE IF NOT EXISTS image (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT NOT NULL,
@@ -26,12 +32,128 @@ func InitDB() *sql.DB {
 		status TEXT CHECK(status IN ('active', 'suspended', 'revoked')) NOT NULL DEFAULT 'active',
 		status_signature TEXT,
 		status_url TEXT
-	)`
+	)`)},
+		// Rows signed before this step used the plain concatenation (version 1).
+		{2, "add image.signature_version", SQL(
+			"ALTER TABLE image ADD COLUMN signature_version INTEGER NOT NULL DEFAULT 1",
+		)},
+		// Rows signed before this step used the hard-coded secret, known as key "legacy".
+		{3, "add image.key_id", SQL(
+			"ALTER TABLE image ADD COLUMN key_id TEXT NOT NULL DEFAULT 'legacy'",
+		)},
+		{4, "create image_audit table", SQL(`
+	CREATE TABLE image_audit (
+		seq INTEGER PRIMARY KEY,
+		image_id INTEGER NOT NULL,
+		image_name TEXT NOT NULL,
+		from_status TEXT NOT NULL,
+		to_status TEXT NOT NULL,
+		actor TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		status_url TEXT,
+		at TEXT NOT NULL,
+		prev_hash TEXT NOT NULL,
+		hash TEXT NOT NULL
+	)`)},
+		{5, "move image contents to the blob store", moveContentsToBlobs(blobs)},
+		// Rows sharing a name become versions 1, 2, ... in the order they were created.
+		{6, "add image.version and make (name, version) unique", SQL(
+			"ALTER TABLE image ADD COLUMN version INTEGER NOT NULL DEFAULT 1",
+			`UPDATE image SET version = (
+			SELECT COUNT(*) FROM image AS older WHERE older.name = image.name AND older.id <= image.id
+		)`,
+			"CREATE UNIQUE INDEX image_name_version ON image (name, version)",
+		)},
+		// Entries written before this step keep their plain SHA-256 hashes until a key rotation.
+		{7, "add image_audit.key_id", SQL(
+			"ALTER TABLE image_audit ADD COLUMN key_id TEXT NOT NULL DEFAULT ''",
+		)},
+	}
+}
 
-	if _, err := db.Exec(createTable); err != nil {
-		log.Fatal(err)
+// moveContentsToBlobs writes every row's contents to blobs and rebuilds the
+// image table with a size column in place of contents. SQLite can't drop a
+// column that other columns are declared around, so the table is copied.
+// Blobs written before a failed step stay behind for GC to remove.
+func moveContentsToBlobs(blobs BlobWriter) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT id, contents FROM image")
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int64
+			var contents string
+			if err := rows.Scan(&id, &contents); err != nil {
+				return err
+			}
+			// The stored sha256 is kept as it is: if the contents were changed
+			// behind the registry's back, verify keeps reporting the row.
+			if _, _, err := blobs.Put(strings.NewReader(contents)); err != nil {
+				return fmt.Errorf("image %d: %w", id, err)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		return SQL(`
+		CREATE TABLE image_new (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			sha256 TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			hmac TEXT NOT NULL,
+			team TEXT NOT NULL,
+			team_owner TEXT NOT NULL,
+			status TEXT CHECK(status IN ('active', 'suspended', 'revoked')) NOT NULL DEFAULT 'active',
+			status_signature TEXT,
+			status_url TEXT,
+			signature_version INTEGER NOT NULL DEFAULT 1,
+			key_id TEXT NOT NULL DEFAULT 'legacy'
+		)`,
+			`INSERT INTO image_new (id, name, sha256, size, hmac, team, team_owner, status, status_signature, status_url, signature_version, key_id)
+		SELECT id, name, sha256, length(CAST(contents AS BLOB)), hmac, team, team_owner, status, status_signature, status_url, signature_version, key_id
+		FROM image`,
+			// Keep the AUTOINCREMENT counter, so IDs of deleted rows are not handed out again.
+			"DELETE FROM sqlite_sequence WHERE name = 'image_new'",
+			"UPDATE sqlite_sequence SET name = 'image_new' WHERE name = 'image'",
+			"DROP TABLE image",
+			"ALTER TABLE image_new RENAME TO image",
+		)(tx)
 	}
+}
 
+// Connect opens the SQLite database at path without touching its schema.
+func Connect(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// Open opens the SQLite database at path and applies any pending migrations.
+func Open(path string, blobs BlobWriter) (*sql.DB, error) {
+	db, err := Connect(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Migrate(db, Migrations(blobs), MigrateOptions{}); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrating %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// InitDB initializes the database and brings its schema up to date.
+func InitDB(blobs BlobWriter) *sql.DB {
+	db, err := Open("images.db", blobs)
+	if err != nil {
+		log.Fatal(err)
+	}
 	return db
 }
 
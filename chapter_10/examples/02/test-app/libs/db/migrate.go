@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this program supports")
+
+// Migration is one step of the schema. Up runs inside a transaction together
+// with the schema_version bookkeeping, so a step is either fully applied or not at all.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+}
+
+// MigrateOptions controls Migrate
+type MigrateOptions struct {
+	DryRun bool // report the pending migrations without applying them
+}
+
+// SQL returns an Up function that runs the statements in order
+func SQL(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+const createSchemaVersion = `
+	CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`
+
+// SchemaVersion returns the highest applied migration, 0 for a new database
+func SchemaVersion(db *sql.DB) (int, error) {
+	var tables int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'").Scan(&tables); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	if tables == 0 {
+		return 0, nil
+	}
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("reading schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Migrate applies the migrations newer than the database's schema version in
+// version order and returns the ones applied (or, with DryRun, pending). It
+// refuses to touch a database migrated by a newer program.
+func Migrate(db *sql.DB, migrations []Migration, opts MigrateOptions) ([]Migration, error) {
+	ordered := append([]Migration(nil), migrations...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Version < ordered[j].Version })
+	for i, m := range ordered {
+		if m.Version < 1 || (i > 0 && m.Version == ordered[i-1].Version) {
+			return nil, fmt.Errorf("invalid migration list: version %d is not positive and unique", m.Version)
+		}
+	}
+	latest := 0
+	if len(ordered) > 0 {
+		latest = ordered[len(ordered)-1].Version
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if current > latest {
+		return nil, fmt.Errorf("%w: database is at version %d, latest known is %d", ErrSchemaTooNew, current, latest)
+	}
+
+	var pending []Migration
+	for _, m := range ordered {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+	if opts.DryRun || len(pending) == 0 {
+		return pending, nil
+	}
+	if _, err := db.Exec(createSchemaVersion); err != nil {
+		return nil, fmt.Errorf("creating schema_version table: %w", err)
+	}
+
+	for i, m := range pending {
+		if err := apply(db, m); err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+	}
+	return pending, nil
+}
+
+func apply(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Description, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,192 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"test-app/utils"
+)
+
+// auditDomain starts every audit hash input
+const auditDomain = "test-app/image-audit"
+
+// GenesisHash is the PrevHash of the first audit entry
+var GenesisHash = strings.Repeat("0", 64)
+
+// AuditEntry records one status change. Hash is an HMAC, under the key
+// named by KeyID, of every other field including PrevHash, the hash of the
+// entry before it, so editing, removing or reordering entries breaks the
+// chain from that point on, and only a key holder can forge a new chain.
+// Entries written before audit hashes were keyed have an empty KeyID and a
+// plain SHA-256, which only detects accidental corruption; Rotate re-keys them.
+type AuditEntry struct {
+	Seq        int64     `json:"seq"`
+	ImageID    int64     `json:"image_id"`
+	ImageName  string    `json:"image_name"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Actor      string    `json:"actor"`
+	Reason     string    `json:"reason"`
+	StatusURL  string    `json:"status_url"`
+	At         time.Time `json:"at"`
+	PrevHash   string    `json:"prev_hash"`
+	Hash       string    `json:"hash"`
+	KeyID      string    `json:"key_id"`
+}
+
+const auditColumns = "seq, image_id, image_name, from_status, to_status, actor, reason, status_url, at, prev_hash, hash, key_id"
+
+// computeHash returns the chain hash of e: an HMAC with secret, or a plain
+// SHA-256 for an unkeyed entry when secret is empty
+func (e AuditEntry) computeHash(secret string) string {
+	input := encodeFields(
+		auditDomain,
+		strconv.FormatInt(e.Seq, 10),
+		strconv.FormatInt(e.ImageID, 10),
+		e.ImageName, e.FromStatus, e.ToStatus, e.Actor, e.Reason, e.StatusURL,
+		e.At.UTC().Format(time.RFC3339Nano),
+		e.PrevHash,
+	)
+	if secret != "" {
+		return utils.GenerateHMAC(secret, string(input))
+	}
+	sum := sha256.Sum256(input)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditSecret returns the secret for the key of e, or "" for an unkeyed entry
+func (r *ImageRepository) auditSecret(e AuditEntry) (string, error) {
+	if e.KeyID == "" {
+		return "", nil
+	}
+	return r.keys.Secret(e.KeyID)
+}
+
+// appendAudit links e to the last entry, hashes it with the active key and
+// stores it inside tx
+func (r *ImageRepository) appendAudit(tx *sql.Tx, e *AuditEntry) error {
+	secret, err := r.keys.Secret(r.keys.Active)
+	if err != nil {
+		return err
+	}
+	e.KeyID = r.keys.Active
+
+	e.Seq, e.PrevHash = 1, GenesisHash
+	err = tx.QueryRow("SELECT seq, hash FROM image_audit ORDER BY seq DESC LIMIT 1").Scan(&e.Seq, &e.PrevHash)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	default:
+		e.Seq++
+	}
+	e.Hash = e.computeHash(secret)
+
+	_, err = tx.Exec("INSERT INTO image_audit ("+auditColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		e.Seq, e.ImageID, e.ImageName, e.FromStatus, e.ToStatus, e.Actor, e.Reason, nullString(e.StatusURL),
+		e.At.UTC().Format(time.RFC3339Nano), e.PrevHash, e.Hash, e.KeyID)
+	return err
+}
+
+// rekeyAudit rehashes the whole chain inside tx with the given key; the
+// chain must have been verified first, see Rotate
+func rekeyAudit(tx *sql.Tx, entries []AuditEntry, keyID, secret string) error {
+	prev := GenesisHash
+	for _, e := range entries {
+		e.PrevHash, e.KeyID = prev, keyID
+		e.Hash = e.computeHash(secret)
+		if _, err := tx.Exec("UPDATE image_audit SET prev_hash = ?, hash = ?, key_id = ? WHERE seq = ?",
+			e.PrevHash, e.Hash, e.KeyID, e.Seq); err != nil {
+			return fmt.Errorf("re-keying audit entry %d: %w", e.Seq, err)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+// AuditLog returns the status changes of the named image, or of every image
+// when name is empty, oldest first
+func (r *ImageRepository) AuditLog(name string) ([]AuditEntry, error) {
+	query := "SELECT " + auditColumns + " FROM image_audit"
+	var args []any
+	if name != "" {
+		query += " WHERE image_name = ?"
+		args = append(args, name)
+	}
+	rows, err := r.db.Query(query+" ORDER BY seq", args...)
+	if err != nil {
+		return nil, fmt.Errorf("reading audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var statusURL sql.NullString
+		var at string
+		if err := rows.Scan(&e.Seq, &e.ImageID, &e.ImageName, &e.FromStatus, &e.ToStatus,
+			&e.Actor, &e.Reason, &statusURL, &at, &e.PrevHash, &e.Hash, &e.KeyID); err != nil {
+			return nil, fmt.Errorf("reading audit log: %w", err)
+		}
+		e.StatusURL = statusURL.String
+		if e.At, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, fmt.Errorf("audit entry %d: %w", e.Seq, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// AuditBreak is an audit entry that does not fit the chain
+type AuditBreak struct {
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+// AuditReport is the outcome of VerifyAudit. Head is the hash of the last
+// entry; keep a copy elsewhere to also detect entries cut off the end.
+type AuditReport struct {
+	Checked int          `json:"checked"`
+	Head    string       `json:"head"`
+	Broken  []AuditBreak `json:"broken"`
+}
+
+// VerifyAudit walks the whole audit log and checks every link of the hash
+// chain. Once an entry is keyed, an unkeyed one after it counts as broken,
+// so the chain can't be downgraded to plain hashes from that point on.
+func (r *ImageRepository) VerifyAudit() (AuditReport, error) {
+	entries, err := r.AuditLog("")
+	if err != nil {
+		return AuditReport{}, err
+	}
+
+	report := AuditReport{Head: GenesisHash, Broken: []AuditBreak{}}
+	prev, wantSeq, keyed := GenesisHash, int64(1), false
+	for _, e := range entries {
+		report.Checked++
+		secret, keyErr := r.auditSecret(e)
+		switch {
+		case e.Seq != wantSeq:
+			report.Broken = append(report.Broken, AuditBreak{e.Seq, fmt.Sprintf("expected entry %d", wantSeq)})
+		case e.PrevHash != prev:
+			report.Broken = append(report.Broken, AuditBreak{e.Seq, "prev_hash does not match the entry before"})
+		case keyErr != nil:
+			report.Broken = append(report.Broken, AuditBreak{e.Seq, keyErr.Error()})
+		case keyed && e.KeyID == "":
+			report.Broken = append(report.Broken, AuditBreak{e.Seq, "unkeyed entry after keyed ones"})
+		case e.computeHash(secret) != e.Hash:
+			report.Broken = append(report.Broken, AuditBreak{e.Seq, "hash does not match the entry"})
+		}
+		prev, wantSeq, keyed = e.Hash, e.Seq+1, keyed || e.KeyID != ""
+	}
+	report.Head = prev
+	return report, nil
+}
@@ -0,0 +1,290 @@
+package registry
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The columnar format keeps rows in groups and each group column by column,
+// the way Parquet does, so a reader can pick out one column without decoding
+// the others:
+//
+//	magic "IMGCOL" version(1)
+//	column count, then per column: name, kind (1 int, 2 string)
+//	per row group: row count, byte length, then per column:
+//	    null bitmap (one bit per row, set = NULL), values of the non-NULL rows
+//	row count 0, total rows, magic "IMGCOL"
+//
+// Counts and lengths are unsigned varints, ints are signed varints and
+// strings are a length followed by the bytes. An empty status_url is
+// written as NULL, like in every other format, see emptyIsNull.
+const (
+	columnarMagic   = "IMGCOL"
+	columnarVersion = 1
+
+	defaultRowGroupSize = 1024
+)
+
+var ErrBadColumnar = errors.New("not a valid columnar export")
+
+type columnarWriter struct {
+	w      io.Writer
+	names  []string
+	kinds  []columnKind
+	size   int     // rows per group
+	header bool    // whether the header has been written
+	group  [][]any // buffered values of the current group, one slice per column
+	rows   int     // rows in the current group
+	total  uint64
+	buf    bytes.Buffer
+}
+
+func newColumnarWriter(w io.Writer, columns []string, kinds []columnKind, rowGroupSize int) *columnarWriter {
+	if rowGroupSize <= 0 {
+		rowGroupSize = defaultRowGroupSize
+	}
+	return &columnarWriter{w: w, names: columns, kinds: kinds, size: rowGroupSize, group: make([][]any, len(kinds))}
+}
+
+func (c *columnarWriter) WriteRow(values []any) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	for i, v := range values {
+		c.group[i] = append(c.group[i], v)
+	}
+	c.rows++
+	if c.rows == c.size {
+		return c.flush()
+	}
+	return nil
+}
+
+// Close writes the last partial row group and the footer
+func (c *columnarWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	if err := c.flush(); err != nil {
+		return err
+	}
+	c.buf.Reset()
+	putUvarint(&c.buf, 0)
+	putUvarint(&c.buf, c.total)
+	c.buf.WriteString(columnarMagic)
+	_, err := c.w.Write(c.buf.Bytes())
+	return err
+}
+
+func (c *columnarWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	c.buf.Reset()
+	c.buf.WriteString(columnarMagic)
+	c.buf.WriteByte(columnarVersion)
+	putUvarint(&c.buf, uint64(len(c.names)))
+	for i, name := range c.names {
+		putString(&c.buf, name)
+		c.buf.WriteByte(byte(c.kinds[i]))
+	}
+	_, err := c.w.Write(c.buf.Bytes())
+	return err
+}
+
+func (c *columnarWriter) flush() error {
+	if c.rows == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	for i, kind := range c.kinds {
+		nulls := make([]byte, (c.rows+7)/8)
+		for row, v := range c.group[i] {
+			if v == nil {
+				nulls[row/8] |= 1 << (row % 8)
+			}
+		}
+		body.Write(nulls)
+		for _, v := range c.group[i] {
+			switch {
+			case v == nil:
+			case kind == kindInt:
+				putVarint(&body, v.(int64))
+			default:
+				putString(&body, v.(string))
+			}
+		}
+		c.group[i] = c.group[i][:0]
+	}
+
+	c.buf.Reset()
+	putUvarint(&c.buf, uint64(c.rows))
+	putUvarint(&c.buf, uint64(body.Len()))
+	c.buf.Write(body.Bytes())
+	c.total += uint64(c.rows)
+	c.rows = 0
+	_, err := c.w.Write(c.buf.Bytes())
+	return err
+}
+
+func putUvarint(buf *bytes.Buffer, n uint64) {
+	buf.Write(binary.AppendUvarint(nil, n))
+}
+
+func putVarint(buf *bytes.Buffer, n int64) {
+	buf.Write(binary.AppendVarint(nil, n))
+}
+
+func putString(buf *bytes.Buffer, s string) {
+	putUvarint(buf, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+// ColumnarReader reads back a columnar export one row group at a time
+type ColumnarReader struct {
+	r       *bufio.Reader
+	columns []string
+	kinds   []columnKind
+	group   [][]any
+	next    int // next row of group to return
+	rows    int
+	read    uint64
+	done    bool
+}
+
+// NewColumnarReader reads the header of a columnar export
+func NewColumnarReader(r io.Reader) (*ColumnarReader, error) {
+	c := &ColumnarReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(columnarMagic)+1)
+	if _, err := io.ReadFull(c.r, magic); err != nil || string(magic[:len(columnarMagic)]) != columnarMagic {
+		return nil, ErrBadColumnar
+	}
+	if magic[len(columnarMagic)] != columnarVersion {
+		return nil, fmt.Errorf("%w: version %d", ErrBadColumnar, magic[len(columnarMagic)])
+	}
+	n, err := c.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < n; i++ {
+		name, err := c.string()
+		if err != nil {
+			return nil, err
+		}
+		kind, err := c.r.ReadByte()
+		if err != nil || (columnKind(kind) != kindInt && columnKind(kind) != kindString) {
+			return nil, fmt.Errorf("%w: bad kind for column %q", ErrBadColumnar, name)
+		}
+		c.columns = append(c.columns, name)
+		c.kinds = append(c.kinds, columnKind(kind))
+	}
+	c.group = make([][]any, len(c.kinds))
+	return c, nil
+}
+
+// Columns returns the column names in file order
+func (c *ColumnarReader) Columns() []string {
+	return c.columns
+}
+
+// Next returns the next row as nil, int64 or string values, or io.EOF after the footer
+func (c *ColumnarReader) Next() ([]any, error) {
+	if c.next == c.rows {
+		if c.done {
+			return nil, io.EOF
+		}
+		if err := c.readGroup(); err != nil {
+			return nil, err
+		}
+		if c.done {
+			return nil, io.EOF
+		}
+	}
+	row := make([]any, len(c.kinds))
+	for i := range c.kinds {
+		row[i] = c.group[i][c.next]
+	}
+	c.next++
+	return row, nil
+}
+
+func (c *ColumnarReader) readGroup() error {
+	rows, err := c.uvarint()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		total, err := c.uvarint()
+		if err != nil {
+			return err
+		}
+		magic := make([]byte, len(columnarMagic))
+		if _, err := io.ReadFull(c.r, magic); err != nil || string(magic) != columnarMagic {
+			return fmt.Errorf("%w: missing footer", ErrBadColumnar)
+		}
+		if total != c.read {
+			return fmt.Errorf("%w: footer counts %d rows, read %d", ErrBadColumnar, total, c.read)
+		}
+		c.done = true
+		c.next, c.rows = 0, 0
+		return nil
+	}
+	if _, err := c.uvarint(); err != nil { // group length, only needed to skip a group
+		return err
+	}
+
+	c.rows, c.next = int(rows), 0
+	for i, kind := range c.kinds {
+		nulls := make([]byte, (c.rows+7)/8)
+		if _, err := io.ReadFull(c.r, nulls); err != nil {
+			return fmt.Errorf("%w: %v", ErrBadColumnar, err)
+		}
+		c.group[i] = c.group[i][:0]
+		for row := 0; row < c.rows; row++ {
+			if nulls[row/8]&(1<<(row%8)) != 0 {
+				c.group[i] = append(c.group[i], nil)
+				continue
+			}
+			var v any
+			if kind == kindInt {
+				v, err = binary.ReadVarint(c.r)
+			} else {
+				v, err = c.string()
+			}
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrBadColumnar, err)
+			}
+			c.group[i] = append(c.group[i], v)
+		}
+	}
+	c.read += rows
+	return nil
+}
+
+func (c *ColumnarReader) uvarint() (uint64, error) {
+	n, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrBadColumnar, err)
+	}
+	return n, nil
+}
+
+func (c *ColumnarReader) string() (string, error) {
+	n, err := c.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if n > 1<<30 {
+		return "", fmt.Errorf("%w: string of %d bytes", ErrBadColumnar, n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(c.r, b); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrBadColumnar, err)
+	}
+	return string(b), nil
+}
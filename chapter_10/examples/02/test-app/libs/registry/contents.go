@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"test-app/libs/blobstore"
+)
+
+// content is a blob in the store together with the HMAC of its bytes
+type content struct {
+	digest string
+	size   int64
+	hmac   string
+}
+
+// storeContents writes the contents of in to the blob store, or checks the
+// blob named by in.Digest, and computes the content HMAC with the active key
+func (r *ImageRepository) storeContents(in ImageInput) (content, error) {
+	secret, err := r.keys.Secret(r.keys.Active)
+	if err != nil {
+		return content{}, err
+	}
+	if in.Body == nil && in.Digest != "" {
+		c := content{digest: in.Digest}
+		if c.hmac, c.size, err = r.readBlob(in.Digest, secret, io.Discard); err != nil {
+			return content{}, fmt.Errorf("contents of %q: %w", in.Name, err)
+		}
+		return c, nil
+	}
+
+	body := in.Body
+	if body == nil {
+		body = strings.NewReader(in.Contents)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	var c content
+	if c.digest, c.size, err = r.blobs.Put(io.TeeReader(body, mac)); err != nil {
+		return content{}, fmt.Errorf("contents of %q: %w", in.Name, err)
+	}
+	c.hmac = hex.EncodeToString(mac.Sum(nil))
+	return c, nil
+}
+
+// readBlob streams the blob with the given digest into w and returns its HMAC
+// under secret and its size. The error is blobstore.ErrDigestMismatch if the
+// blob no longer matches its digest; the HMAC and size are still filled in.
+func (r *ImageRepository) readBlob(digest, secret string, w io.Writer) (string, int64, error) {
+	blob, err := r.blobs.Open(digest)
+	if err != nil {
+		return "", 0, err
+	}
+	defer blob.Close()
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	size, err := io.Copy(io.MultiWriter(mac, w), blob)
+	return hex.EncodeToString(mac.Sum(nil)), size, err
+}
+
+// OpenContents streams the contents of the latest version of the named image
+// from the blob store. Reading fails with blobstore.ErrDigestMismatch at the
+// end if the blob was changed.
+func (r *ImageRepository) OpenContents(name string) (io.ReadCloser, Image, error) {
+	img, err := getImage(r.db, name)
+	if err != nil {
+		return nil, Image{}, err
+	}
+	return r.openBlob(img)
+}
+
+// OpenVersion is OpenContents for one version of the image
+func (r *ImageRepository) OpenVersion(name string, version int) (io.ReadCloser, Image, error) {
+	img, err := r.GetVersion(name, version)
+	if err != nil {
+		return nil, Image{}, err
+	}
+	return r.openBlob(img)
+}
+
+func (r *ImageRepository) openBlob(img Image) (io.ReadCloser, Image, error) {
+	blob, err := r.blobs.Open(img.SHA256)
+	if err != nil {
+		return nil, Image{}, fmt.Errorf("contents of %q version %d: %w", img.Name, img.Version, err)
+	}
+	return blob, img, nil
+}
+
+// ReadContents returns the contents of the named image; use OpenContents for large blobs
+func (r *ImageRepository) ReadContents(name string) (string, error) {
+	blob, _, err := r.OpenContents(name)
+	if err != nil {
+		return "", err
+	}
+	defer blob.Close()
+	var contents strings.Builder
+	if _, err := io.Copy(&contents, blob); err != nil {
+		return "", fmt.Errorf("contents of %q: %w", name, err)
+	}
+	return contents.String(), nil
+}
+
+// CollectGarbage removes the blobs no image refers to, see blobstore.Store.GC
+func (r *ImageRepository) CollectGarbage(opts blobstore.GCOptions) (blobstore.GCReport, error) {
+	rows, err := r.db.Query("SELECT DISTINCT sha256 FROM image")
+	if err != nil {
+		return blobstore.GCReport{}, fmt.Errorf("collecting blobs: %w", err)
+	}
+	defer rows.Close()
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var digest string
+		if err := rows.Scan(&digest); err != nil {
+			return blobstore.GCReport{}, fmt.Errorf("collecting blobs: %w", err)
+		}
+		referenced[digest] = true
+	}
+	if err := rows.Err(); err != nil {
+		return blobstore.GCReport{}, fmt.Errorf("collecting blobs: %w", err)
+	}
+	return r.blobs.GC(referenced, opts)
+}
@@ -0,0 +1,346 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownFormat    = errors.New("unknown export format, choose csv, jsonl or columnar")
+	ErrUnknownColumn    = errors.New("unknown column")
+	ErrManifestMismatch = errors.New("export does not match its manifest")
+)
+
+// ExportFormat is the file format written by Export
+type ExportFormat string
+
+const (
+	FormatCSV      ExportFormat = "csv"      // header row, NULL as an empty field, see emptyIsNull
+	FormatJSONL    ExportFormat = "jsonl"    // one JSON object per line, NULL as null
+	FormatColumnar ExportFormat = "columnar" // row groups stored column by column, see columnar.go
+)
+
+// columnKind is the type of an exported column
+type columnKind byte
+
+const (
+	kindInt    columnKind = 1
+	kindString columnKind = 2
+)
+
+// exportColumns lists every column Export can write, in table order
+var exportColumns = []struct {
+	name string
+	kind columnKind
+}{
+	{"id", kindInt},
+	{"name", kindString},
+	{"sha256", kindString},
+	{"size", kindInt},
+	{"hmac", kindString},
+	{"team", kindString},
+	{"team_owner", kindString},
+	{"status", kindString},
+	{"status_signature", kindString},
+	{"status_url", kindString},
+	{"signature_version", kindInt},
+	{"key_id", kindString},
+	{"version", kindInt},
+}
+
+// emptyIsNull lists the nullable columns. The registry stores an empty
+// value in them as NULL (see nullString) and signs both the same way, so an
+// empty string that got into the table some other way is exported as NULL
+// too. Every format then agrees, including csv, which can't tell them apart.
+var emptyIsNull = map[string]bool{"status_url": true}
+
+// ExportColumns returns the names of the columns Export can write, in table order
+func ExportColumns() []string {
+	names := make([]string, len(exportColumns))
+	for i, c := range exportColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// ExportFilter limits an export to matching rows; empty fields match everything
+type ExportFilter struct {
+	Team      string `json:"team,omitempty"`
+	TeamOwner string `json:"team_owner,omitempty"`
+	Status    string `json:"status,omitempty"`
+}
+
+// ExportOptions chooses the format, rows and columns of an export
+type ExportOptions struct {
+	Format  ExportFormat // defaults to csv
+	Filter  ExportFilter
+	Columns []string // defaults to ExportColumns()
+	// RowGroupSize is the number of rows per columnar row group, default 1024
+	RowGroupSize int
+}
+
+// ExportManifest describes a finished export so it can be checked later
+type ExportManifest struct {
+	Format     ExportFormat `json:"format"`
+	Columns    []string     `json:"columns"`
+	Filter     ExportFilter `json:"filter"`
+	Rows       int64        `json:"rows"`
+	Bytes      int64        `json:"bytes"`
+	SHA256     string       `json:"sha256"`
+	ExportedAt time.Time    `json:"exported_at"`
+}
+
+// Check reads an export back and compares its size and SHA-256 with the manifest
+func (m ExportManifest) Check(r io.Reader) error {
+	hash := sha256.New()
+	n, err := io.Copy(hash, r)
+	if err != nil {
+		return fmt.Errorf("checking export: %w", err)
+	}
+	if n != m.Bytes {
+		return fmt.Errorf("%w: %d bytes, manifest says %d", ErrManifestMismatch, n, m.Bytes)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != m.SHA256 {
+		return fmt.Errorf("%w: sha256 %s, manifest says %s", ErrManifestMismatch, sum, m.SHA256)
+	}
+	return nil
+}
+
+// rowWriter writes exported rows in one format. Values are nil for NULL,
+// int64 for kindInt columns and string otherwise.
+type rowWriter interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+// Export streams the matching rows to w one at a time and returns a manifest
+// with the row count, size and SHA-256 of what was written
+func (r *ImageRepository) Export(w io.Writer, opts ExportOptions) (ExportManifest, error) {
+	if opts.Format == "" {
+		opts.Format = FormatCSV
+	}
+	if len(opts.Columns) == 0 {
+		opts.Columns = ExportColumns()
+	}
+	kinds, err := columnKinds(opts.Columns)
+	if err != nil {
+		return ExportManifest{}, err
+	}
+	if opts.Filter.Status != "" && !validStatus(opts.Filter.Status) {
+		return ExportManifest{}, fmt.Errorf("%w (got %q)", ErrInvalidStatus, opts.Filter.Status)
+	}
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(w, hash)}
+	var out rowWriter
+	switch opts.Format {
+	case FormatCSV:
+		out = newCSVWriter(counter, opts.Columns)
+	case FormatJSONL:
+		out = newJSONLWriter(counter, opts.Columns)
+	case FormatColumnar:
+		out = newColumnarWriter(counter, opts.Columns, kinds, opts.RowGroupSize)
+	default:
+		return ExportManifest{}, fmt.Errorf("%w (got %q)", ErrUnknownFormat, opts.Format)
+	}
+
+	query, args := exportQuery(opts.Columns, opts.Filter)
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return ExportManifest{}, fmt.Errorf("exporting images: %w", err)
+	}
+	defer rows.Close()
+
+	manifest := ExportManifest{Format: opts.Format, Columns: opts.Columns, Filter: opts.Filter, ExportedAt: time.Now().UTC()}
+	dest := make([]any, len(kinds))
+	for i := range dest {
+		dest[i] = new(any)
+	}
+	values := make([]any, len(kinds))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return ExportManifest{}, fmt.Errorf("exporting images: %w", err)
+		}
+		for i, kind := range kinds {
+			if values[i], err = exportValue(*dest[i].(*any), kind); err != nil {
+				return ExportManifest{}, fmt.Errorf("exporting images: column %s: %w", opts.Columns[i], err)
+			}
+			if values[i] == "" && emptyIsNull[opts.Columns[i]] {
+				values[i] = nil
+			}
+		}
+		if err := out.WriteRow(values); err != nil {
+			return ExportManifest{}, fmt.Errorf("exporting images: %w", err)
+		}
+		manifest.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return ExportManifest{}, fmt.Errorf("exporting images: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return ExportManifest{}, fmt.Errorf("exporting images: %w", err)
+	}
+
+	manifest.Bytes = counter.n
+	manifest.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return manifest, nil
+}
+
+// ExportCSV writes every image as CSV with a header row; NULLs, and the empty
+// values the registry treats as NULL, are written as empty fields
+func (r *ImageRepository) ExportCSV(w io.Writer) error {
+	_, err := r.Export(w, ExportOptions{Format: FormatCSV})
+	return err
+}
+
+func columnKinds(columns []string) ([]columnKind, error) {
+	kinds := make([]columnKind, len(columns))
+	seen := make(map[string]bool)
+	for i, name := range columns {
+		if seen[name] {
+			return nil, fmt.Errorf("column %q selected twice", name)
+		}
+		seen[name] = true
+		for _, c := range exportColumns {
+			if c.name == name {
+				kinds[i] = c.kind
+			}
+		}
+		if kinds[i] == 0 {
+			return nil, fmt.Errorf("%w %q, choose from %s", ErrUnknownColumn, name, strings.Join(ExportColumns(), ", "))
+		}
+	}
+	return kinds, nil
+}
+
+// exportQuery builds the SELECT for an export. Column names have been checked
+// against exportColumns, so only the filter values need to be parameters.
+func exportQuery(columns []string, filter ExportFilter) (string, []any) {
+	var where []string
+	var args []any
+	for _, f := range []struct{ column, value string }{
+		{"team", filter.Team},
+		{"team_owner", filter.TeamOwner},
+		{"status", filter.Status},
+	} {
+		if f.value != "" {
+			where = append(where, f.column+" = ?")
+			args = append(args, f.value)
+		}
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM image"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	return query + " ORDER BY id", args
+}
+
+// exportValue converts a scanned value to nil, int64 or string
+func exportValue(v any, kind columnKind) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case int64:
+		if kind == kindString {
+			return strconv.FormatInt(v, 10), nil
+		}
+		return v, nil
+	case []byte:
+		return exportValue(string(v), kind)
+	case string:
+		if kind == kindInt {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			return n, nil
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unexpected value of type %T", v)
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, columns []string) *csvWriter {
+	out := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(columns))}
+	out.w.Write(columns)
+	return out
+}
+
+func (c *csvWriter) WriteRow(values []any) error {
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+			c.record[i] = ""
+		case int64:
+			c.record[i] = strconv.FormatInt(v, 10)
+		case string:
+			c.record[i] = v
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlWriter struct {
+	w    io.Writer
+	keys [][]byte // the quoted column names followed by a colon
+	line []byte
+}
+
+func newJSONLWriter(w io.Writer, columns []string) *jsonlWriter {
+	out := &jsonlWriter{w: w, keys: make([][]byte, len(columns))}
+	for i, name := range columns {
+		quoted, _ := json.Marshal(name)
+		out.keys[i] = append(quoted, ':')
+	}
+	return out
+}
+
+// WriteRow writes the columns in the selected order, which encoding a map would not keep
+func (j *jsonlWriter) WriteRow(values []any) error {
+	j.line = append(j.line[:0], '{')
+	for i, v := range values {
+		if i > 0 {
+			j.line = append(j.line, ',')
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		j.line = append(append(j.line, j.keys[i]...), encoded...)
+	}
+	j.line = append(j.line, '}', '\n')
+	_, err := j.w.Write(j.line)
+	return err
+}
+
+func (j *jsonlWriter) Close() error { return nil }
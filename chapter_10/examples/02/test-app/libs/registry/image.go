@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	ErrNotFound      = errors.New("image not found")
+	ErrAlreadyExists = errors.New("image already exists, use Update to add a version")
+	ErrInvalidStatus = errors.New("invalid status, choose active, suspended or revoked")
+	ErrMissingField  = errors.New("missing required field")
+)
+
+// Statuses allowed by the image table
+const (
+	StatusActive    = "active"
+	StatusSuspended = "suspended"
+	StatusRevoked   = "revoked"
+)
+
+// Image is one row of the image table: one version of the image with its name
+type Image struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	Version         int    `json:"version"` // 1 for the first, one more for each Update
+	SHA256          string `json:"sha256"`  // digest of the contents, which live in the blob store
+	Size            int64  `json:"size"`
+	HMAC            string `json:"hmac"`
+	Team            string `json:"team"`
+	TeamOwner       string `json:"team_owner"`
+	Status          string `json:"status"`
+	StatusSignature string `json:"status_signature"`
+	StatusURL       string `json:"status_url"` // empty when NULL
+	// SignatureVersion is the encoding StatusSignature was computed with, see SigningInput
+	SignatureVersion int `json:"signature_version"`
+	// KeyID names the keyring entry HMAC and StatusSignature were computed with
+	KeyID string `json:"key_id"`
+}
+
+// ImageInput holds the caller-supplied fields of an image; the hashes and
+// signature are computed by the repository. The contents come from Body if
+// set, else from the stored blob named by Digest if set, else from Contents.
+type ImageInput struct {
+	Name      string
+	Contents  string
+	Body      io.Reader
+	Digest    string
+	Team      string
+	TeamOwner string
+	Status    string // defaults to active
+	StatusURL string
+}
+
+// validate checks the required fields and fills in the default status
+func (in *ImageInput) validate() error {
+	for field, value := range map[string]string{"name": in.Name, "team": in.Team, "team_owner": in.TeamOwner} {
+		if value == "" {
+			return fmt.Errorf("%w: %s", ErrMissingField, field)
+		}
+	}
+	if in.Status == "" {
+		in.Status = StatusActive
+	}
+	if !validStatus(in.Status) {
+		return fmt.Errorf("%w (got %q)", ErrInvalidStatus, in.Status)
+	}
+	return nil
+}
+
+func validStatus(status string) bool {
+	switch status {
+	case StatusActive, StatusSuspended, StatusRevoked:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,338 @@
+package registry
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidDuplicatePolicy = errors.New("invalid duplicate policy, choose skip or update")
+	ErrChecksumMismatch       = errors.New("contents do not match the sha256 column")
+)
+
+// DuplicatePolicy decides what an import does with a name that already exists
+type DuplicatePolicy string
+
+const (
+	DuplicateSkip   DuplicatePolicy = "skip"   // keep the stored image
+	DuplicateUpdate DuplicatePolicy = "update" // add a version with the new contents, team and owner, as Update does
+)
+
+const defaultBatchSize = 500
+
+// ImportOptions controls how records are written
+type ImportOptions struct {
+	OnDuplicate DuplicatePolicy // defaults to skip
+	BatchSize   int             // records per transaction, default 500
+	// Team and TeamOwner are used for files found by ImportDir
+	Team      string
+	TeamOwner string
+}
+
+// ImportFailure is a record that could not be imported
+type ImportFailure struct {
+	Source string `json:"source"` // line number or file path
+	Name   string `json:"name"`
+	Err    string `json:"error"`
+}
+
+// ImportReport counts what an import did with each record
+type ImportReport struct {
+	Inserted int             `json:"inserted"`
+	Updated  int             `json:"updated"`
+	Skipped  int             `json:"skipped"` // duplicates left alone, or unchanged
+	Failed   []ImportFailure `json:"failed"`
+}
+
+// importRecord is one parsed record waiting to be written
+type importRecord struct {
+	source string
+	in     ImageInput
+	sha256 string // from an exported file; checked against the contents when set
+	err    error  // set when the record could not be parsed
+}
+
+// ImportDir walks root and imports every regular file, named by its path
+// relative to root with forward slashes
+func (r *ImageRepository) ImportDir(root string, opts ImportOptions) (ImportReport, error) {
+	if opts.Team == "" || opts.TeamOwner == "" {
+		return ImportReport{}, fmt.Errorf("%w: team and team_owner are needed for a directory import", ErrMissingField)
+	}
+
+	var walkErr error
+	paths := make(chan string)
+	go func() {
+		defer close(paths)
+		walkErr = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				paths <- path
+			}
+			return nil
+		})
+	}()
+
+	report, err := r.importRecords(func() (importRecord, error) {
+		path, ok := <-paths
+		if !ok {
+			return importRecord{}, io.EOF
+		}
+		rel, _ := filepath.Rel(root, path)
+		record := importRecord{source: path, in: ImageInput{Name: filepath.ToSlash(rel), Team: opts.Team, TeamOwner: opts.TeamOwner}}
+		// importRecord closes the file once it is in the blob store.
+		file, err := os.Open(path)
+		if err != nil {
+			record.err = err
+		} else {
+			record.in.Body = file
+		}
+		return record, nil
+	}, opts)
+	for range paths {
+		// drain the walk if the import stopped early
+	}
+	if err == nil && walkErr != nil {
+		err = fmt.Errorf("importing %s: %w", root, walkErr)
+	}
+	return report, err
+}
+
+// ImportCSV reads images in the format written by Export with FormatCSV, or
+// any CSV with a contents column. Columns are matched by name; id, version,
+// hmac and the signature columns are ignored and computed again. Without a contents column
+// the sha256 column must name a blob already in the store.
+func (r *ImageRepository) ImportCSV(src io.Reader, opts ImportOptions) (ImportReport, error) {
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return ImportReport{}, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return ImportReport{}, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "team", "team_owner"} {
+		if _, ok := columns[required]; !ok {
+			return ImportReport{}, fmt.Errorf("CSV header is missing the %q column", required)
+		}
+	}
+	_, hasContents := columns["contents"]
+	if _, hasDigest := columns["sha256"]; !hasContents && !hasDigest {
+		return ImportReport{}, errors.New(`CSV header needs a "contents" or a "sha256" column`)
+	}
+
+	return r.importRecords(func() (importRecord, error) {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return importRecord{}, io.EOF
+		}
+		if err != nil {
+			// No field positions are recorded for a record that failed to parse.
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return importRecord{source: fmt.Sprintf("line %d", parseErr.StartLine), err: parseErr.Err}, nil
+			}
+			return importRecord{}, err
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+		record := importRecord{
+			source: fmt.Sprintf("line %d", line),
+			in: ImageInput{
+				Name:      field("name"),
+				Team:      field("team"),
+				TeamOwner: field("team_owner"),
+				Status:    field("status"),
+				StatusURL: field("status_url"),
+			},
+		}
+		if hasContents {
+			record.in.Contents, record.sha256 = field("contents"), field("sha256")
+		} else {
+			record.in.Digest = field("sha256")
+		}
+		return record, nil
+	}, opts)
+}
+
+// ImportJSONL reads images in the format written by Export with FormatJSONL.
+// As with ImportCSV, a record without "contents" refers to a stored blob by "sha256".
+func (r *ImageRepository) ImportJSONL(src io.Reader, opts ImportOptions) (ImportReport, error) {
+	reader := bufio.NewReader(src)
+	line := 0
+	return r.importRecords(func() (importRecord, error) {
+		for {
+			data, err := reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				return importRecord{}, err
+			}
+			if len(data) == 0 && err == io.EOF {
+				return importRecord{}, io.EOF
+			}
+			line++
+			if strings.TrimSpace(string(data)) == "" {
+				continue
+			}
+
+			var row struct {
+				Name      string  `json:"name"`
+				Contents  *string `json:"contents"`
+				Team      string  `json:"team"`
+				TeamOwner string  `json:"team_owner"`
+				Status    string  `json:"status"`
+				StatusURL string  `json:"status_url"`
+				SHA256    string  `json:"sha256"`
+			}
+			record := importRecord{source: fmt.Sprintf("line %d", line)}
+			if record.err = json.Unmarshal(data, &row); record.err == nil {
+				record.in = ImageInput{Name: row.Name, Team: row.Team, TeamOwner: row.TeamOwner, Status: row.Status, StatusURL: row.StatusURL}
+				if row.Contents != nil {
+					record.in.Contents, record.sha256 = *row.Contents, row.SHA256
+				} else {
+					record.in.Digest = row.SHA256
+				}
+			}
+			return record, nil
+		}
+	}, opts)
+}
+
+// ImportFile imports path as JSON Lines (.jsonl, .json) or CSV, or walks it if it is a directory
+func (r *ImageRepository) ImportFile(path string, opts ImportOptions) (ImportReport, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	if info.IsDir() {
+		return r.ImportDir(path, opts)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	defer file.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".json":
+		return r.ImportJSONL(file, opts)
+	}
+	return r.ImportCSV(file, opts)
+}
+
+// importRecords writes records in transactions of opts.BatchSize. A record
+// that fails is reported and the rest of its batch still goes in; an error
+// reading the source or committing stops the import, keeping earlier batches.
+func (r *ImageRepository) importRecords(next func() (importRecord, error), opts ImportOptions) (ImportReport, error) {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = DuplicateSkip
+	}
+	if opts.OnDuplicate != DuplicateSkip && opts.OnDuplicate != DuplicateUpdate {
+		return ImportReport{}, fmt.Errorf("%w (got %q)", ErrInvalidDuplicatePolicy, opts.OnDuplicate)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+
+	var report ImportReport
+	for done := false; !done; {
+		tx, err := r.db.Begin()
+		if err != nil {
+			return report, fmt.Errorf("importing images: %w", err)
+		}
+		var batch ImportReport
+		for n := 0; n < opts.BatchSize; n++ {
+			record, err := next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				tx.Rollback()
+				return report, fmt.Errorf("importing images: %w", err)
+			}
+			if err := r.importRecord(tx, record, opts.OnDuplicate, &batch); err != nil {
+				batch.Failed = append(batch.Failed, ImportFailure{Source: record.source, Name: record.in.Name, Err: err.Error()})
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return report, fmt.Errorf("importing images: %w", err)
+		}
+		report.Inserted += batch.Inserted
+		report.Updated += batch.Updated
+		report.Skipped += batch.Skipped
+		report.Failed = append(report.Failed, batch.Failed...)
+	}
+	return report, nil
+}
+
+func (r *ImageRepository) importRecord(tx *sql.Tx, record importRecord, policy DuplicatePolicy, report *ImportReport) error {
+	if closer, ok := record.in.Body.(io.Closer); ok {
+		defer closer.Close()
+	}
+	if record.err != nil {
+		return record.err
+	}
+
+	existing, err := getImage(tx, record.in.Name)
+	found := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if found && policy == DuplicateSkip {
+		report.Skipped++
+		return nil
+	}
+	if !found {
+		if err := record.in.validate(); err != nil {
+			return err
+		}
+	}
+
+	c, err := r.storeContents(record.in)
+	if err != nil {
+		return err
+	}
+	if record.sha256 != "" && record.sha256 != c.digest {
+		return ErrChecksumMismatch
+	}
+	if !found {
+		if _, err := r.insert(tx, newImage(record.in, c)); err != nil {
+			return err
+		}
+		report.Inserted++
+		return nil
+	}
+
+	unchanged := existing.SHA256 == c.digest && existing.Team == record.in.Team &&
+		existing.TeamOwner == record.in.TeamOwner && (record.in.Status == "" || existing.Status == record.in.Status)
+	if unchanged {
+		report.Skipped++
+		return nil
+	}
+	if _, err := r.update(tx, record.in.Name, record.in, &c); err != nil {
+		return err
+	}
+	report.Updated++
+	return nil
+}
@@ -0,0 +1,116 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LegacyKeyID is the key ID given to rows signed before key IDs were stored
+const LegacyKeyID = "legacy"
+
+var (
+	ErrUnknownKey = errors.New("unknown key ID")
+	ErrNoKeys     = errors.New("no signing keys configured")
+)
+
+// Keyring holds the HMAC secrets by key ID. New rows are signed with the
+// active key; existing rows are verified with the key named in the row.
+type Keyring struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// Validate checks that there is at least one key and that the active key exists
+func (k *Keyring) Validate() error {
+	if len(k.Keys) == 0 {
+		return ErrNoKeys
+	}
+	for id, secret := range k.Keys {
+		if id == "" || secret == "" {
+			return fmt.Errorf("keyring: key IDs and secrets must not be empty")
+		}
+	}
+	if _, ok := k.Keys[k.Active]; !ok {
+		return fmt.Errorf("keyring: active key %q: %w", k.Active, ErrUnknownKey)
+	}
+	return nil
+}
+
+// Secret returns the secret for a key ID
+func (k *Keyring) Secret(id string) (string, error) {
+	secret, ok := k.Keys[id]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return secret, nil
+}
+
+// IDs returns the key IDs in sorted order
+func (k *Keyring) IDs() []string {
+	ids := make([]string, 0, len(k.Keys))
+	for id := range k.Keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LoadKeyring reads a JSON keyring file:
+//
+//	{"active": "2025-01", "keys": {"legacy": "supersecretkey", "2025-01": "..."}}
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading keyring: %w", err)
+	}
+	var k Keyring
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, fmt.Errorf("parsing keyring %s: %w", path, err)
+	}
+	if err := k.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &k, nil
+}
+
+// ParseKeyring reads keys written as "id=secret,id=secret"; the active key is
+// active, or the only key if there is just one
+func ParseKeyring(keys, active string) (*Keyring, error) {
+	k := Keyring{Active: active, Keys: make(map[string]string)}
+	for _, pair := range strings.Split(keys, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("keyring: expected id=secret, got %q", pair)
+		}
+		k.Keys[strings.TrimSpace(id)] = secret
+		if len(k.Keys) == 1 && active == "" {
+			k.Active = strings.TrimSpace(id)
+		}
+	}
+	if active == "" && len(k.Keys) > 1 {
+		return nil, fmt.Errorf("keyring: %d keys given, choose the active one", len(k.Keys))
+	}
+	if err := k.Validate(); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// KeyringFromEnv loads the keyring named by IMAGE_KEYRING, or else parses
+// IMAGE_KEYS ("id=secret,...") with IMAGE_ACTIVE_KEY picking the signing key
+func KeyringFromEnv() (*Keyring, error) {
+	if path := os.Getenv("IMAGE_KEYRING"); path != "" {
+		return LoadKeyring(path)
+	}
+	if keys := os.Getenv("IMAGE_KEYS"); keys != "" {
+		return ParseKeyring(keys, os.Getenv("IMAGE_ACTIVE_KEY"))
+	}
+	return nil, fmt.Errorf("%w: set IMAGE_KEYRING to a keyring file or IMAGE_KEYS to id=secret pairs", ErrNoKeys)
+}
@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTransition = errors.New("status change not allowed")
+
+// allowedTransitions lists where each status may move; revoked is final
+var allowedTransitions = map[string][]string{
+	StatusActive:    {StatusSuspended, StatusRevoked},
+	StatusSuspended: {StatusActive, StatusRevoked},
+	StatusRevoked:   {},
+}
+
+// CanTransition reports whether an image may move from one status to another
+func CanTransition(from, to string) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition describes who changes an image's status and why
+type Transition struct {
+	Actor     string
+	Reason    string
+	StatusURL string // e.g. the advisory behind a suspension; replaces the image's status_url
+}
+
+// Suspend moves an active image to suspended
+func (r *ImageRepository) Suspend(name string, t Transition) (Image, error) {
+	return r.changeStatus(name, StatusSuspended, t)
+}
+
+// Reinstate moves a suspended image back to active
+func (r *ImageRepository) Reinstate(name string, t Transition) (Image, error) {
+	return r.changeStatus(name, StatusActive, t)
+}
+
+// Revoke moves an active or suspended image to revoked, for good
+func (r *ImageRepository) Revoke(name string, t Transition) (Image, error) {
+	return r.changeStatus(name, StatusRevoked, t)
+}
+
+// changeStatus re-signs the latest version of the image with its new status,
+// in place, and appends the audit entry in the same transaction. The row
+// keeps the key it was signed with, since its content HMAC is under that
+// key; Rotate moves rows to the active key.
+func (r *ImageRepository) changeStatus(name, to string, t Transition) (Image, error) {
+	if strings.TrimSpace(t.Actor) == "" {
+		return Image{}, fmt.Errorf("%w: actor", ErrMissingField)
+	}
+	if strings.TrimSpace(t.Reason) == "" {
+		return Image{}, fmt.Errorf("%w: reason", ErrMissingField)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Image{}, fmt.Errorf("changing status of %q: %w", name, err)
+	}
+	defer tx.Rollback()
+
+	img, err := getImage(tx, name)
+	if err != nil {
+		return Image{}, err
+	}
+	from := img.Status
+	if !CanTransition(from, to) {
+		return Image{}, fmt.Errorf("%q: %w from %s to %s", name, ErrInvalidTransition, from, to)
+	}
+	// Re-signing a tampered row would make it look valid again.
+	if result := r.VerifyImage(img); !result.OK() {
+		return Image{}, fmt.Errorf("%q: %w on %s", name, ErrVerificationFailed, strings.Join(result.Failed, ", "))
+	}
+
+	img.Status = to
+	img.StatusURL = t.StatusURL
+	if err := r.signWith(&img, CurrentSignatureVersion, img.KeyID, ""); err != nil {
+		return Image{}, err
+	}
+	if _, err := tx.Exec(`
+		UPDATE image SET status = ?, status_url = ?, hmac = ?, status_signature = ?, signature_version = ?, key_id = ?
+		WHERE id = ?`,
+		img.Status, nullString(img.StatusURL), img.HMAC, img.StatusSignature, img.SignatureVersion, img.KeyID, img.ID); err != nil {
+		return Image{}, fmt.Errorf("changing status of %q: %w", name, err)
+	}
+
+	entry := AuditEntry{
+		ImageID:    img.ID,
+		ImageName:  img.Name,
+		FromStatus: from,
+		ToStatus:   to,
+		Actor:      t.Actor,
+		Reason:     t.Reason,
+		StatusURL:  t.StatusURL,
+		At:         time.Now().UTC(),
+	}
+	if err := r.appendAudit(tx, &entry); err != nil {
+		return Image{}, fmt.Errorf("auditing status change of %q: %w", name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return Image{}, fmt.Errorf("changing status of %q: %w", name, err)
+	}
+	return img, nil
+}
+
+// queryer is satisfied by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// getImage reads the latest version of the image with the given name, or returns ErrNotFound
+func getImage(q queryer, name string) (Image, error) {
+	row := q.QueryRow("SELECT "+imageColumns+" FROM image WHERE name = ? ORDER BY version DESC LIMIT 1", name)
+	img, err := scanImage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Image{}, fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("reading image %q: %w", name, err)
+	}
+	return img, nil
+}
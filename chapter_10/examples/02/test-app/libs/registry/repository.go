@@ -0,0 +1,256 @@
+package registry
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"test-app/libs/blobstore"
+)
+
+// imageColumns lists the columns in the order scanImage reads them
+const imageColumns = "id, name, sha256, size, hmac, team, team_owner, status, status_signature, status_url, signature_version, key_id, version"
+
+// ImageRepository reads and writes the image table; the contents of each
+// image live in blobs under their SHA-256
+type ImageRepository struct {
+	db    *sql.DB
+	keys  *Keyring
+	blobs *blobstore.Store
+}
+
+// NewImageRepository returns a repository that signs new images with the
+// active key of keys and verifies stored ones with the key they name
+func NewImageRepository(db *sql.DB, keys *Keyring, blobs *blobstore.Store) *ImageRepository {
+	return &ImageRepository{db: db, keys: keys, blobs: blobs}
+}
+
+// Create stores the contents in the blob store, then signs and stores version 1
+// of a new image. It returns ErrAlreadyExists if the name is taken.
+func (r *ImageRepository) Create(in ImageInput) (Image, error) {
+	if err := in.validate(); err != nil {
+		return Image{}, err
+	}
+	c, err := r.storeContents(in)
+	if err != nil {
+		return Image{}, err
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Image{}, fmt.Errorf("creating image %q: %w", in.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := getImage(tx, in.Name); err == nil {
+		return Image{}, fmt.Errorf("%q: %w", in.Name, ErrAlreadyExists)
+	} else if !errors.Is(err, ErrNotFound) {
+		return Image{}, err
+	}
+	img, err := r.insert(tx, newImage(in, c))
+	if err != nil {
+		return Image{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Image{}, fmt.Errorf("creating image %q: %w", in.Name, err)
+	}
+	return img, nil
+}
+
+// newImage returns version 1 of a validated image whose contents are already in the blob store
+func newImage(in ImageInput, c content) Image {
+	return Image{
+		Name:      in.Name,
+		Version:   1,
+		SHA256:    c.digest,
+		Size:      c.size,
+		HMAC:      c.hmac,
+		Team:      in.Team,
+		TeamOwner: in.TeamOwner,
+		Status:    in.Status,
+		StatusURL: in.StatusURL,
+	}
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insert signs img and stores it as a new row. The unique index on
+// (name, version) rejects a version that is already taken.
+func (r *ImageRepository) insert(db execer, img Image) (Image, error) {
+	if err := r.sign(&img); err != nil {
+		return Image{}, err
+	}
+
+	result, err := db.Exec(`
+		INSERT INTO image (name, version, sha256, size, hmac, team, team_owner, status, status_signature, status_url, signature_version, key_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		img.Name, img.Version, img.SHA256, img.Size, img.HMAC, img.Team, img.TeamOwner, img.Status, img.StatusSignature, nullString(img.StatusURL), img.SignatureVersion, img.KeyID)
+	if err != nil {
+		return Image{}, fmt.Errorf("storing image %q version %d: %w", img.Name, img.Version, err)
+	}
+	if img.ID, err = result.LastInsertId(); err != nil {
+		return Image{}, fmt.Errorf("storing image %q version %d: %w", img.Name, img.Version, err)
+	}
+	return img, nil
+}
+
+// Get returns the latest version of the image with the given name, or ErrNotFound
+func (r *ImageRepository) Get(name string) (Image, error) {
+	return getImage(r.db, name)
+}
+
+// GetVersion returns one version of an image, or ErrNotFound. An older
+// version keeps the status it had when it was superseded; the status of the
+// image is the one Get returns.
+func (r *ImageRepository) GetVersion(name string, version int) (Image, error) {
+	row := r.db.QueryRow("SELECT "+imageColumns+" FROM image WHERE name = ? AND version = ?", name, version)
+	img, err := scanImage(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Image{}, fmt.Errorf("%q version %d: %w", name, version, ErrNotFound)
+	}
+	if err != nil {
+		return Image{}, fmt.Errorf("reading image %q version %d: %w", name, version, err)
+	}
+	return img, nil
+}
+
+// History returns every version of the image with the given name, oldest
+// first, or ErrNotFound
+func (r *ImageRepository) History(name string) ([]Image, error) {
+	images, err := r.query("SELECT "+imageColumns+" FROM image WHERE name = ? ORDER BY version", name)
+	if err != nil {
+		return nil, fmt.Errorf("reading history of %q: %w", name, err)
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+	return images, nil
+}
+
+// Update stores a new version of an image with the given contents, team and
+// owner; earlier versions are kept, see History. The status carries over:
+// in.Status must be empty or the current status, status changes (and
+// status_url) go through Suspend, Reinstate and Revoke. Revoked images can't
+// be updated.
+func (r *ImageRepository) Update(name string, in ImageInput) (Image, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return Image{}, fmt.Errorf("updating image %q: %w", name, err)
+	}
+	defer tx.Rollback()
+
+	img, err := r.update(tx, name, in, nil)
+	if err != nil {
+		return Image{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Image{}, fmt.Errorf("updating image %q: %w", name, err)
+	}
+	return img, nil
+}
+
+// update applies Update inside tx. The contents are written to the blob
+// store unless c says where they already are.
+func (r *ImageRepository) update(tx *sql.Tx, name string, in ImageInput, c *content) (Image, error) {
+	img, err := getImage(tx, name)
+	if err != nil {
+		return Image{}, err
+	}
+	if result := r.VerifyImage(img); !result.OK() {
+		return Image{}, fmt.Errorf("%q: %w on %s", name, ErrVerificationFailed, strings.Join(result.Failed, ", "))
+	}
+	if img.Status == StatusRevoked {
+		return Image{}, fmt.Errorf("%q: %w: image is revoked", name, ErrInvalidTransition)
+	}
+	if in.Status != "" && in.Status != img.Status {
+		return Image{}, fmt.Errorf("%q: %w: use Suspend, Reinstate or Revoke to change the status", name, ErrInvalidTransition)
+	}
+
+	in.Name, in.Status = name, img.Status
+	if err := in.validate(); err != nil {
+		return Image{}, err
+	}
+	if c == nil {
+		stored, err := r.storeContents(in)
+		if err != nil {
+			return Image{}, err
+		}
+		c = &stored
+	}
+	next := img
+	next.Version++
+	next.SHA256, next.Size, next.HMAC = c.digest, c.size, c.hmac
+	next.Team, next.TeamOwner = in.Team, in.TeamOwner
+	return r.insert(tx, next)
+}
+
+// Delete removes every version of the image with the given name, or returns ErrNotFound
+func (r *ImageRepository) Delete(name string) error {
+	result, err := r.db.Exec("DELETE FROM image WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("deleting image %q: %w", name, err)
+	}
+	return expectRows(result, name)
+}
+
+// List returns every version of every image, oldest first
+func (r *ImageRepository) List() ([]Image, error) {
+	images, err := r.query("SELECT " + imageColumns + " FROM image ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("listing images: %w", err)
+	}
+	return images, nil
+}
+
+func (r *ImageRepository) query(query string, args ...any) ([]Image, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []Image
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanImage(row scanner) (Image, error) {
+	var img Image
+	var signature, statusURL sql.NullString
+	err := row.Scan(&img.ID, &img.Name, &img.SHA256, &img.Size, &img.HMAC,
+		&img.Team, &img.TeamOwner, &img.Status, &signature, &statusURL, &img.SignatureVersion, &img.KeyID, &img.Version)
+	img.StatusSignature = signature.String
+	img.StatusURL = statusURL.String
+	return img, err
+}
+
+// expectRows turns an UPDATE or DELETE that touched nothing into ErrNotFound
+func expectRows(result sql.Result, name string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("image %q: %w", name, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,219 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"test-app/utils"
+)
+
+// Status signature encodings. Version 1 concatenated the fields, so
+// ("ab", "c") and ("a", "bc") signed the same bytes; version 2 prefixes every
+// field with its length. Version 3 covers the digest and size instead of the
+// contents, which moved to the blob store, and version 4 adds the image
+// version so an old row can't be renumbered to pass as the latest. Rows keep the version they were
+// signed with until Resign moves them to CurrentSignatureVersion.
+const (
+	SignatureV1             = 1
+	SignatureV2             = 2
+	SignatureV3             = 3
+	SignatureV4             = 4
+	CurrentSignatureVersion = SignatureV4
+)
+
+var (
+	ErrUnknownSignatureVersion = errors.New("unknown signature version")
+	ErrVerificationFailed      = errors.New("verification failed")
+)
+
+// signatureDomain starts every length-prefixed input (version 2 and later), so
+// the bytes can't be mistaken for another kind of signed message
+const signatureDomain = "test-app/image-status-signature"
+
+// SigningInput returns the bytes covered by the status signature of img.
+// contents is only part of versions 1 and 2; later versions ignore it.
+func SigningInput(version int, img Image, contents string) ([]byte, error) {
+	switch version {
+	case SignatureV1:
+		return []byte(img.Name + contents + img.SHA256 + img.HMAC + img.Team + img.TeamOwner + img.Status + img.StatusURL), nil
+	case SignatureV2:
+		return encodeFields(
+			signatureDomain, strconv.Itoa(version),
+			img.Name, contents, img.SHA256, img.HMAC, img.Team, img.TeamOwner, img.Status, img.StatusURL,
+		), nil
+	case SignatureV3:
+		return encodeFields(
+			signatureDomain, strconv.Itoa(version),
+			img.Name, img.SHA256, strconv.FormatInt(img.Size, 10), img.HMAC, img.Team, img.TeamOwner, img.Status, img.StatusURL,
+		), nil
+	case SignatureV4:
+		return encodeFields(
+			signatureDomain, strconv.Itoa(version),
+			img.Name, strconv.Itoa(img.Version), img.SHA256, strconv.FormatInt(img.Size, 10), img.HMAC, img.Team, img.TeamOwner, img.Status, img.StatusURL,
+		), nil
+	}
+	return nil, fmt.Errorf("%w %d", ErrUnknownSignatureVersion, version)
+}
+
+// encodeFields writes each field prefixed with its 8-byte big-endian length,
+// so no two different field lists encode to the same bytes
+func encodeFields(fields ...string) []byte {
+	var buf bytes.Buffer
+	for _, field := range fields {
+		binary.Write(&buf, binary.BigEndian, uint64(len(field)))
+		buf.WriteString(field)
+	}
+	return buf.Bytes()
+}
+
+// signWith fills in the status signature of img using the given encoding
+// version and key; contents is needed for versions 1 and 2 only. The
+// digest, size and HMAC must already be set, see storeContents.
+func (r *ImageRepository) signWith(img *Image, version int, keyID, contents string) error {
+	secret, err := r.keys.Secret(keyID)
+	if err != nil {
+		return err
+	}
+	input, err := SigningInput(version, *img, contents)
+	if err != nil {
+		return err
+	}
+	img.StatusSignature = utils.GenerateHMAC(secret, string(input))
+	img.SignatureVersion = version
+	img.KeyID = keyID
+	return nil
+}
+
+// sign signs img with the current encoding and the active key
+func (r *ImageRepository) sign(img *Image) error {
+	return r.signWith(img, CurrentSignatureVersion, r.keys.Active, "")
+}
+
+// ResignReport is the outcome of Resign
+type ResignReport struct {
+	Resigned int            `json:"resigned"`
+	Skipped  []VerifyResult `json:"skipped"` // rows that failed verification and were left alone
+}
+
+// Resign re-signs every row still on an older signature version with the
+// current one, in a single transaction. Rows that fail verification are
+// skipped so tampering is not laundered into a fresh signature. With dryRun
+// nothing is written.
+func (r *ImageRepository) Resign(dryRun bool) (ResignReport, error) {
+	report := ResignReport{Skipped: []VerifyResult{}}
+	images, err := r.List()
+	if err != nil {
+		return report, fmt.Errorf("re-signing images: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return report, fmt.Errorf("re-signing images: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, img := range images {
+		if img.SignatureVersion == CurrentSignatureVersion {
+			continue
+		}
+		if result := r.VerifyImage(img); !result.OK() {
+			report.Skipped = append(report.Skipped, result)
+			continue
+		}
+
+		if err := r.signWith(&img, CurrentSignatureVersion, img.KeyID, ""); err != nil {
+			return ResignReport{}, fmt.Errorf("re-signing image %d: %w", img.ID, err)
+		}
+		if _, err := tx.Exec("UPDATE image SET status_signature = ?, signature_version = ? WHERE id = ?",
+			img.StatusSignature, img.SignatureVersion, img.ID); err != nil {
+			return ResignReport{}, fmt.Errorf("re-signing image %d: %w", img.ID, err)
+		}
+		report.Resigned++
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return ResignReport{}, fmt.Errorf("re-signing images: %w", err)
+	}
+	return report, nil
+}
+
+// RotateReport is the outcome of Rotate
+type RotateReport struct {
+	KeyID        string `json:"key_id"`
+	Rotated      int    `json:"rotated"`
+	AuditEntries int    `json:"audit_entries"`
+}
+
+// Rotate re-signs every row with keyID and the current encoding, and re-keys
+// the audit chain, in a single transaction, so the old keys can be retired
+// afterwards. Every row and the audit chain must verify under their current
+// keys first; otherwise nothing is changed. The content HMACs are
+// recomputed by streaming each blob.
+func (r *ImageRepository) Rotate(keyID string) (RotateReport, error) {
+	secret, err := r.keys.Secret(keyID)
+	if err != nil {
+		return RotateReport{}, err
+	}
+	images, err := r.List()
+	if err != nil {
+		return RotateReport{}, fmt.Errorf("rotating keys: %w", err)
+	}
+
+	var tampered []string
+	for _, img := range images {
+		if result := r.VerifyImage(img); !result.OK() {
+			tampered = append(tampered, fmt.Sprintf("%d (%s)", img.ID, strings.Join(result.Failed, ", ")))
+		}
+	}
+	if len(tampered) > 0 {
+		return RotateReport{}, fmt.Errorf("rotating keys: %w: image %s", ErrVerificationFailed, strings.Join(tampered, "; image "))
+	}
+	audit, err := r.VerifyAudit()
+	if err != nil {
+		return RotateReport{}, fmt.Errorf("rotating keys: %w", err)
+	}
+	if len(audit.Broken) > 0 {
+		return RotateReport{}, fmt.Errorf("rotating keys: %w: audit entry %d: %s", ErrVerificationFailed, audit.Broken[0].Seq, audit.Broken[0].Reason)
+	}
+	entries, err := r.AuditLog("")
+	if err != nil {
+		return RotateReport{}, fmt.Errorf("rotating keys: %w", err)
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return RotateReport{}, fmt.Errorf("rotating keys: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := RotateReport{KeyID: keyID}
+	for _, img := range images {
+		if img.HMAC, _, err = r.readBlob(img.SHA256, secret, io.Discard); err != nil {
+			return RotateReport{}, fmt.Errorf("rotating image %d: %w", img.ID, err)
+		}
+		if err := r.signWith(&img, CurrentSignatureVersion, keyID, ""); err != nil {
+			return RotateReport{}, err
+		}
+		if _, err := tx.Exec("UPDATE image SET hmac = ?, status_signature = ?, signature_version = ?, key_id = ? WHERE id = ?",
+			img.HMAC, img.StatusSignature, img.SignatureVersion, img.KeyID, img.ID); err != nil {
+			return RotateReport{}, fmt.Errorf("rotating image %d: %w", img.ID, err)
+		}
+		report.Rotated++
+	}
+	if err := rekeyAudit(tx, entries, keyID, secret); err != nil {
+		return RotateReport{}, err
+	}
+	report.AuditEntries = len(entries)
+	if err := tx.Commit(); err != nil {
+		return RotateReport{}, fmt.Errorf("rotating keys: %w", err)
+	}
+	return report, nil
+}
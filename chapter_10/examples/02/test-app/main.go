@@ -1,116 +1,93 @@
 package main
 
 import (
-    "encoding/csv"
-    "database/sql"
+    "errors"
     "fmt"
     "log"
     "os"
 
+    "test-app/cli"
+    "test-app/libs/blobstore"
     "test-app/libs/db"
-    "test-app/utils"
+    "test-app/libs/registry"
 )
 
-func createImage(dbConn *sql.DB, name, contents, team, teamOwner, secretKey, status, statusURL string) {
-    sha256Hash := utils.GenerateHash(contents)
-    hmacHash := utils.GenerateHMAC(secretKey, contents)
-    dataToSign := name + contents + sha256Hash + hmacHash + team + teamOwner + status + statusURL
-    statusSignature := utils.GenerateHMAC(secretKey, dataToSign)
+func main() {
+    dbPath := os.Getenv("IMAGE_DB")
+    if dbPath == "" {
+        dbPath = "images.db"
+    }
+    blobDir := os.Getenv("IMAGE_BLOBS")
+    if blobDir == "" {
+        blobDir = "blobs"
+    }
 
-    insertSQL := `
-    INSERT INTO image (name, contents, sha256, hmac, team, team_owner, status, status_signature, status_url)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+    // Any arguments run a single subcommand instead of the example,
+    // e.g. `test-app migrate --dry-run` or `test-app verify`.
+    if len(os.Args) > 1 {
+        os.Exit(cli.Run(cli.Config{DBPath: dbPath, BlobDir: blobDir}, os.Args[1:], os.Stdout, os.Stderr))
+    }
 
-    _, err := dbConn.Exec(insertSQL, name, contents, sha256Hash, hmacHash, team, teamOwner, status, statusSignature, statusURL)
+    // IMAGE_KEYRING or IMAGE_KEYS, e.g. IMAGE_KEYS=legacy=supersecretkey
+    keys, err := registry.KeyringFromEnv()
     if err != nil {
         log.Fatal(err)
     }
-}
-
-func readImage(dbConn *sql.DB, name string) {
-    querySQL := "SELECT * FROM image WHERE name = ?"
-    row := dbConn.QueryRow(querySQL, name)
-
-    var id int
-    var imageName, contents, sha256Hash, hmacHash, team, teamOwner, status, statusSignature, statusURL string
-    if err := row.Scan(&id, &imageName, &contents, &sha256Hash, &hmacHash, &team, &teamOwner, &status, &statusSignature, &statusURL); err != nil {
-        if err == sql.ErrNoRows {
-            fmt.Println("No record found.")
-        } else {
-            log.Fatal(err)
-        }
-    } else {
-        fmt.Printf("Image Record: %+v\n", map[string]interface{}{
-            "id":               id,
-            "name":             imageName,
-            "contents":         contents,
-            "sha256":           sha256Hash,
-            "hmac":             hmacHash,
-            "team":             team,
-            "team_owner":       teamOwner,
-            "status":           status,
-            "status_signature": statusSignature,
-            "status_url":       statusURL,
-        })
-    }
-}
 
-func exportToCSV(dbConn *sql.DB, filePath string) {
-    querySQL := "SELECT * FROM image"
-    rows, err := dbConn.Query(querySQL)
+    // Image contents live outside the database, under their SHA-256
+    blobs, err := blobstore.New(blobDir)
     if err != nil {
         log.Fatal(err)
     }
-    defer rows.Close()
 
-    file, err := os.Create(filePath)
+    dbConn, err := db.Open(dbPath, blobs)
     if err != nil {
         log.Fatal(err)
     }
-    defer file.Close()
+    defer db.CloseDB(dbConn)
 
-    writer := csv.NewWriter(file)
-    defer writer.Flush()
+    images := registry.NewImageRepository(dbConn, keys, blobs)
 
-    columns, err := rows.Columns()
+    // Create example; names are unique, so later runs add a version instead
+    example := registry.ImageInput{
+        Name:      "example_image",
+        Contents:  "example_data",
+        Team:      "team_a",
+        TeamOwner: "owner_a",
+    }
+    _, err = images.Create(example)
+    if errors.Is(err, registry.ErrAlreadyExists) {
+        _, err = images.Update(example.Name, example)
+    }
     if err != nil {
         log.Fatal(err)
     }
-    writer.Write(columns)
-
-    record := make([]interface{}, len(columns))
-    recordPointers := make([]interface{}, len(columns))
-    for i := range record {
-        recordPointers[i] = &record[i]
-    }
 
-    for rows.Next() {
-        if err := rows.Scan(recordPointers...); err != nil {
+    // Read example
+    img, err := images.Get("example_image")
+    switch {
+    case errors.Is(err, registry.ErrNotFound):
+        fmt.Println("No record found.")
+    case err != nil:
+        log.Fatal(err)
+    default:
+        fmt.Printf("Image Record: %+v\n", img)
+        contents, err := images.ReadContents(img.Name)
+        if err != nil {
             log.Fatal(err)
         }
-
-        rowData := make([]string, len(columns))
-        for i, value := range record {
-            rowData[i] = fmt.Sprintf("%v", value)
-        }
-        writer.Write(rowData)
+        fmt.Printf("Contents: %s\n", contents)
     }
-}
-
-func main() {
-    dbConn := db.InitDB()
-    defer db.CloseDB(dbConn)
-
-    secretKey := "supersecretkey"
-
-    // Create example
-    createImage(dbConn, "example_image", "example_data", "team_a", "owner_a", secretKey, "active", "")
-
-    // Read example
-    readImage(dbConn, "example_image")
 
     // Export to CSV example
-    exportToCSV(dbConn, "images_export.csv")
+    file, err := os.Create("images_export.csv")
+    if err != nil {
+        log.Fatal(err)
+    }
+    defer file.Close()
+    if err := images.ExportCSV(file); err != nil {
+        log.Fatal(err)
+    }
     fmt.Println("Data exported to images_export.csv")
 }
 
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"test-app/libs/blobstore"
+	"test-app/utils"
+)
+
+// TestBlobStore checks sharded paths, deduplication and verified reads
+func TestBlobStore(t *testing.T) {
+	root := t.TempDir()
+	blobs, err := blobstore.New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	digest, size, err := blobs.Put(strings.NewReader("example_data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != utils.GenerateHash("example_data") || size != 12 {
+		t.Errorf("got %s, %d", digest, size)
+	}
+	path, _ := blobs.Path(digest)
+	if want := filepath.Join(root, "sha256", digest[:2], digest[2:4], digest); path != want {
+		t.Errorf("path %s, want %s", path, want)
+	}
+	if again, _, _ := blobs.Put(strings.NewReader("example_data")); again != digest {
+		t.Errorf("second put: got %s", again)
+	}
+
+	blob, err := blobs.Open(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil || string(data) != "example_data" {
+		t.Errorf("read %q, %v", data, err)
+	}
+
+	os.WriteFile(path, []byte("example_dat4"), 0o600)
+	blob, _ = blobs.Open(digest)
+	if _, err := io.ReadAll(blob); !errors.Is(err, blobstore.ErrDigestMismatch) {
+		t.Errorf("changed blob: got %v, want ErrDigestMismatch", err)
+	}
+	blob.Close()
+
+	if _, err := blobs.Open(utils.GenerateHash("other")); !errors.Is(err, blobstore.ErrNotFound) {
+		t.Errorf("missing blob: got %v, want ErrNotFound", err)
+	}
+	if _, err := blobs.Open("../../etc/passwd"); !errors.Is(err, blobstore.ErrInvalidDigest) {
+		t.Errorf("bad digest: got %v, want ErrInvalidDigest", err)
+	}
+}
+
+// TestBlobStoreGC removes only unreferenced blobs older than MinAge
+func TestBlobStoreGC(t *testing.T) {
+	blobs, err := blobstore.New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _, _ := blobs.Put(strings.NewReader("kept"))
+	old, _, _ := blobs.Put(strings.NewReader("old"))
+	recent, _, _ := blobs.Put(strings.NewReader("recent"))
+	for _, digest := range []string{kept, old} {
+		path, _ := blobs.Path(digest)
+		past := time.Now().Add(-2 * time.Hour)
+		os.Chtimes(path, past, past)
+	}
+	referenced := map[string]bool{kept: true}
+
+	report, err := blobs.GC(referenced, blobstore.GCOptions{DryRun: true, MinAge: time.Hour})
+	if err != nil || len(report.Removed) != 1 || report.Removed[0] != old {
+		t.Fatalf("dry run: got %+v, %v", report, err)
+	}
+	if _, err := blobs.Stat(old); err != nil {
+		t.Error("dry run removed a blob")
+	}
+
+	if _, err := blobs.GC(referenced, blobstore.GCOptions{MinAge: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	for digest, want := range map[string]bool{kept: true, old: false, recent: true} {
+		if _, err := blobs.Stat(digest); (err == nil) != want {
+			t.Errorf("blob %s present: %v, want %v", digest[:8], err == nil, want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"test-app/libs/registry"
+)
+
+// exportFixture stores three images across two teams; only "b" has a status URL
+func exportFixture(t *testing.T) *registry.ImageRepository {
+	t.Helper()
+	images := newRepository(t)
+	for _, in := range []registry.ImageInput{
+		{Name: "a", Contents: "1", Team: "team_a", TeamOwner: "owner_a"},
+		{Name: "b", Contents: "2", Team: "team_b", TeamOwner: "owner_b", Status: registry.StatusSuspended, StatusURL: "https://example.com/b"},
+		{Name: "c", Contents: "3", Team: "team_a", TeamOwner: "owner_c"},
+	} {
+		if _, err := images.Create(in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return images
+}
+
+// TestExportJSONL checks NULL handling, filters and column order
+func TestExportJSONL(t *testing.T) {
+	images := exportFixture(t)
+
+	var out bytes.Buffer
+	manifest, err := images.Export(&out, registry.ExportOptions{
+		Format:  registry.FormatJSONL,
+		Filter:  registry.ExportFilter{Team: "team_a"},
+		Columns: []string{"name", "status_url", "id"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"name":"a","status_url":null,"id":1}` + "\n" + `{"name":"c","status_url":null,"id":3}` + "\n"
+	if out.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if manifest.Rows != 2 || manifest.Bytes != int64(out.Len()) {
+		t.Errorf("manifest %+v does not describe the output", manifest)
+	}
+	if err := manifest.Check(bytes.NewReader(out.Bytes())); err != nil {
+		t.Error(err)
+	}
+	if err := manifest.Check(strings.NewReader(strings.Replace(out.String(), "a", "x", 1))); !errors.Is(err, registry.ErrManifestMismatch) {
+		t.Errorf("changed export: got %v, want ErrManifestMismatch", err)
+	}
+}
+
+// TestExportCSVFilters checks that filters combine and NULLs become empty fields
+func TestExportCSVFilters(t *testing.T) {
+	images := exportFixture(t)
+
+	var out bytes.Buffer
+	_, err := images.Export(&out, registry.ExportOptions{
+		Filter:  registry.ExportFilter{TeamOwner: "owner_b", Status: registry.StatusSuspended},
+		Columns: []string{"name", "status", "status_url"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name,status,status_url\nb,suspended,https://example.com/b\n"; out.String() != want {
+		t.Errorf("got %q, want %q", out.String(), want)
+	}
+
+	if _, err := images.Export(io.Discard, registry.ExportOptions{Columns: []string{"name", "password"}}); !errors.Is(err, registry.ErrUnknownColumn) {
+		t.Errorf("got %v, want ErrUnknownColumn", err)
+	}
+	if _, err := images.Export(io.Discard, registry.ExportOptions{Format: "xml"}); !errors.Is(err, registry.ErrUnknownFormat) {
+		t.Errorf("got %v, want ErrUnknownFormat", err)
+	}
+}
+
+// TestExportColumnar reads a columnar export back across several row groups
+func TestExportColumnar(t *testing.T) {
+	images := exportFixture(t)
+
+	var out bytes.Buffer
+	manifest, err := images.Export(&out, registry.ExportOptions{
+		Format:       registry.FormatColumnar,
+		Columns:      []string{"id", "name", "status_url", "signature_version"},
+		RowGroupSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Rows != 3 {
+		t.Errorf("manifest counts %d rows, want 3", manifest.Rows)
+	}
+
+	reader, err := registry.NewColumnarReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reader.Columns(), manifest.Columns) {
+		t.Errorf("columns %v, want %v", reader.Columns(), manifest.Columns)
+	}
+	var got [][]any
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	want := [][]any{
+		{int64(1), "a", nil, int64(4)},
+		{int64(2), "b", "https://example.com/b", int64(4)},
+		{int64(3), "c", nil, int64(4)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	truncated := out.Bytes()[:out.Len()-3]
+	reader, err = registry.NewColumnarReader(bytes.NewReader(truncated))
+	for err == nil {
+		_, err = reader.Next()
+	}
+	if !errors.Is(err, registry.ErrBadColumnar) {
+		t.Errorf("truncated file: got %v, want ErrBadColumnar", err)
+	}
+}
+
+// TestExportEmptyStatusURL checks that an empty status_url is exported as
+// NULL in every format, since the registry can't tell the two apart
+func TestExportEmptyStatusURL(t *testing.T) {
+	conn, blobs := openDB(t)
+	images := registry.NewImageRepository(conn, testKeys, blobs)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+	conn.Exec("UPDATE image SET status_url = ''")
+	columns := []string{"name", "status_url"}
+
+	var out bytes.Buffer
+	if _, err := images.Export(&out, registry.ExportOptions{Format: registry.FormatJSONL, Columns: columns}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"name":"example_image","status_url":null}` + "\n"; out.String() != want {
+		t.Errorf("jsonl: got %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if _, err := images.Export(&out, registry.ExportOptions{Format: registry.FormatColumnar, Columns: columns}); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := registry.NewColumnarReader(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row, err := reader.Next(); err != nil || row[1] != nil {
+		t.Errorf("columnar: got %v, %v, want a NULL status_url", row, err)
+	}
+}
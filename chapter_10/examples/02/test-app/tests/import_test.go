@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"test-app/libs/blobstore"
+	"test-app/libs/db"
+	"test-app/libs/registry"
+	"test-app/utils"
+)
+
+// TestImportRoundTrip loads CSV and JSON Lines exports into an empty registry
+// sharing the blob store; exports carry the digests, not the contents
+func TestImportRoundTrip(t *testing.T) {
+	conn, blobs := openDB(t)
+	source := registry.NewImageRepository(conn, testKeys, blobs)
+	if _, err := source.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []registry.ExportFormat{registry.FormatCSV, registry.FormatJSONL} {
+		var out bytes.Buffer
+		if _, err := source.Export(&out, registry.ExportOptions{Format: format}); err != nil {
+			t.Fatal(err)
+		}
+
+		copyDB, err := db.Open(filepath.Join(t.TempDir(), "copy.db"), blobs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer copyDB.Close()
+		images := registry.NewImageRepository(copyDB, testKeys, blobs)
+		importFn := images.ImportCSV
+		if format == registry.FormatJSONL {
+			importFn = images.ImportJSONL
+		}
+		report, err := importFn(&out, registry.ImportOptions{BatchSize: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Inserted != 1 || len(report.Failed) != 0 {
+			t.Errorf("%s: got %+v, want 1 inserted", format, report)
+		}
+		img, err := images.Get("example_image")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contents, _ := images.ReadContents(img.Name); contents != "example_data" || !images.VerifyImage(img).OK() {
+			t.Errorf("%s: imported image %+v with contents %q", format, img, contents)
+		}
+
+		// A second registry without the blobs can't resolve the digests.
+		report, err = newRepository(t).ImportCSV(strings.NewReader("name,sha256,team,team_owner\nx,"+img.SHA256+",t,o\n"), registry.ImportOptions{})
+		if err != nil || len(report.Failed) != 1 || !strings.Contains(report.Failed[0].Err, blobstore.ErrNotFound.Error()) {
+			t.Errorf("missing blob: got %+v, %v", report, err)
+		}
+	}
+}
+
+// TestImportDuplicatesAndFailures checks the skip and update policies and that
+// bad records are reported without stopping the rest
+func TestImportDuplicatesAndFailures(t *testing.T) {
+	images := exportFixture(t)
+	input := "name,contents,team,team_owner,sha256\n" +
+		"a,1,team_a,owner_a,\n" + // unchanged
+		"c,new,team_a,owner_c,\n" + // changed
+		"d,4,team_d,owner_d," + utils.GenerateHash("4") + "\n" +
+		"e,5,team_e,owner_e,not-the-hash\n" +
+		"f,6,,owner_f,\n"
+
+	report, err := images.ImportCSV(strings.NewReader(input), registry.ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 1 || report.Skipped != 2 || len(report.Failed) != 2 {
+		t.Errorf("skip: got %+v", report)
+	}
+	if report.Failed[0].Source != "line 5" || report.Failed[1].Name != "f" {
+		t.Errorf("failures: got %+v", report.Failed)
+	}
+
+	report, err = images.ImportCSV(strings.NewReader(input), registry.ImportOptions{OnDuplicate: registry.DuplicateUpdate})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 1 || report.Skipped != 2 || len(report.Failed) != 2 {
+		t.Errorf("update: got %+v", report)
+	}
+	if img, _ := images.Get("c"); img.SHA256 != utils.GenerateHash("new") || img.Size != 3 {
+		t.Errorf("c was not updated: %+v", img)
+	}
+}
+
+// TestImportMalformedCSV checks that a row the CSV reader can't parse is
+// reported by line and the rows around it are still imported
+func TestImportMalformedCSV(t *testing.T) {
+	images := newRepository(t)
+	input := "name,contents,team,team_owner\n" +
+		"a,1,team_a,owner_a\n" +
+		"b\"x,2,team_b,owner_b\n" +
+		"c,3,team_c,owner_c\n"
+
+	report, err := images.ImportCSV(strings.NewReader(input), registry.ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 2 || len(report.Failed) != 1 || report.Failed[0].Source != "line 3" {
+		t.Errorf("got %+v", report)
+	}
+	if _, err := images.Get("c"); err != nil {
+		t.Errorf("row after the malformed one: %v", err)
+	}
+}
+
+// TestImportDir names each file by its path under the root
+func TestImportDir(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "sub"), 0o755)
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("alpha"), 0o644)
+	os.WriteFile(filepath.Join(root, "sub", "b.txt"), []byte("beta"), 0o644)
+
+	images := newRepository(t)
+	report, err := images.ImportDir(root, registry.ImportOptions{Team: "team_a", TeamOwner: "owner_a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 2 {
+		t.Errorf("got %+v, want 2 inserted", report)
+	}
+	img, err := images.Get("sub/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents, _ := images.ReadContents("sub/b.txt"); contents != "beta" || img.HMAC != utils.GenerateHMAC(secretKey, "beta") {
+		t.Errorf("imported file: %+v with contents %q", img, contents)
+	}
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"test-app/libs/registry"
+)
+
+// TestParseKeyring checks the IMAGE_KEYS format
+func TestParseKeyring(t *testing.T) {
+	keys, err := registry.ParseKeyring("legacy=supersecretkey", "")
+	if err != nil || keys.Active != "legacy" {
+		t.Errorf("single key: got %+v, %v", keys, err)
+	}
+	if _, err := registry.ParseKeyring("a=1,b=2", ""); err == nil {
+		t.Error("two keys without an active one: expected an error")
+	}
+	if _, err := registry.ParseKeyring("a=1,b=2", "c"); !errors.Is(err, registry.ErrUnknownKey) {
+		t.Errorf("unknown active key: got %v, want ErrUnknownKey", err)
+	}
+	if _, err := registry.ParseKeyring("", ""); !errors.Is(err, registry.ErrNoKeys) {
+		t.Errorf("no keys: got %v, want ErrNoKeys", err)
+	}
+}
+
+// TestRotate re-signs every row and the audit chain under a new key, and
+// refuses when a row is tampered with
+func TestRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	os.WriteFile(path, []byte(`{"active": "k1", "keys": {"k1": "first", "k2": "second"}}`), 0o600)
+	keys, err := registry.LoadKeyring(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, blobs := openDB(t)
+	images := registry.NewImageRepository(conn, keys, blobs)
+	for _, name := range []string{"one", "two"} {
+		in := exampleInput()
+		in.Name = name
+		if _, err := images.Create(in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	images.Suspend("one", byAdmin)
+	images.Reinstate("one", byAdmin)
+
+	report, err := images.Rotate("k2")
+	if err != nil || report.Rotated != 2 || report.AuditEntries != 2 {
+		t.Fatalf("got %+v, %v", report, err)
+	}
+
+	// Only the new key is needed from now on.
+	rotated := registry.NewImageRepository(conn, &registry.Keyring{Active: "k2", Keys: map[string]string{"k2": "second"}}, blobs)
+	if verified, err := rotated.VerifyAll(); err != nil || len(verified.Tampered) != 0 {
+		t.Errorf("after rotation: got %+v, %v", verified, err)
+	}
+	if audit, err := rotated.VerifyAudit(); err != nil || audit.Checked != 2 || len(audit.Broken) != 0 {
+		t.Errorf("audit after rotation: got %+v, %v", audit, err)
+	}
+
+	conn.Exec("UPDATE image SET team = 'team_x' WHERE name = 'two'")
+	if _, err := images.Rotate("k1"); !errors.Is(err, registry.ErrVerificationFailed) {
+		t.Errorf("rotating with a tampered row: got %v, want ErrVerificationFailed", err)
+	}
+	img, _ := images.Get("one")
+	if img.KeyID != "k2" {
+		t.Errorf("failed rotation changed key of intact row to %q", img.KeyID)
+	}
+	if _, err := images.Rotate("k3"); !errors.Is(err, registry.ErrUnknownKey) {
+		t.Errorf("unknown key: got %v, want ErrUnknownKey", err)
+	}
+}
+
+// TestStatusChangeAfterKeySwitch changes the status of a row signed with a
+// key that is no longer the active one
+func TestStatusChangeAfterKeySwitch(t *testing.T) {
+	conn, blobs := openDB(t)
+	keys := &registry.Keyring{Active: "k1", Keys: map[string]string{"k1": "first", "k2": "second"}}
+	images := registry.NewImageRepository(conn, keys, blobs)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+
+	keys.Active = "k2"
+	for _, change := range []func(string, registry.Transition) (registry.Image, error){images.Suspend, images.Reinstate} {
+		img, err := change("example_image", byAdmin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := images.VerifyImage(img); !result.OK() {
+			t.Errorf("%s under key %s: failed %v", img.Status, img.KeyID, result.Failed)
+		}
+	}
+
+	if _, err := images.Rotate("k2"); err != nil {
+		t.Fatal(err)
+	}
+	img, err := images.Suspend("example_image", byAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := images.VerifyImage(img); !result.OK() || img.KeyID != "k2" {
+		t.Errorf("after rotation: key %s, failed %v", img.KeyID, result.Failed)
+	}
+}
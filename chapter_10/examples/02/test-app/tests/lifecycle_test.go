@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"test-app/libs/registry"
+)
+
+var byAdmin = registry.Transition{Actor: "alice", Reason: "CVE-2025-0001", StatusURL: "https://example.com/cve"}
+
+// TestStatusTransitions walks an image through suspend, reinstate and revoke
+func TestStatusTransitions(t *testing.T) {
+	images := newRepository(t)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		change func(string, registry.Transition) (registry.Image, error)
+		want   string
+		err    error
+	}{
+		{images.Reinstate, "", registry.ErrInvalidTransition}, // already active
+		{images.Suspend, registry.StatusSuspended, nil},
+		{images.Suspend, "", registry.ErrInvalidTransition},
+		{images.Reinstate, registry.StatusActive, nil},
+		{images.Revoke, registry.StatusRevoked, nil},
+		{images.Reinstate, "", registry.ErrInvalidTransition}, // no un-revoking
+		{images.Suspend, "", registry.ErrInvalidTransition},
+	}
+	for i, step := range steps {
+		img, err := step.change("example_image", byAdmin)
+		if !errors.Is(err, step.err) {
+			t.Fatalf("step %d: got %v, want %v", i, err, step.err)
+		}
+		if err == nil && (img.Status != step.want || !images.VerifyImage(img).OK()) {
+			t.Errorf("step %d: got status %q (verified %v), want %q", i, img.Status, images.VerifyImage(img).OK(), step.want)
+		}
+	}
+
+	if _, err := images.Update("example_image", exampleInput()); !errors.Is(err, registry.ErrInvalidTransition) {
+		t.Errorf("updating a revoked image: got %v, want ErrInvalidTransition", err)
+	}
+	if _, err := images.Suspend("example_image", registry.Transition{Actor: "alice"}); !errors.Is(err, registry.ErrMissingField) {
+		t.Errorf("missing reason: got %v, want ErrMissingField", err)
+	}
+
+	log, err := images.AuditLog("example_image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(log) != 3 || log[0].FromStatus != "active" || log[2].ToStatus != "revoked" || log[1].Actor != "alice" {
+		t.Errorf("unexpected audit log %+v", log)
+	}
+}
+
+// TestAuditChain checks that editing or removing an audit entry is detected
+func TestAuditChain(t *testing.T) {
+	conn, blobs := openDB(t)
+	images := registry.NewImageRepository(conn, testKeys, blobs)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+	images.Suspend("example_image", byAdmin)
+	images.Reinstate("example_image", byAdmin)
+	images.Suspend("example_image", byAdmin)
+
+	report, err := images.VerifyAudit()
+	if err != nil || report.Checked != 3 || len(report.Broken) != 0 {
+		t.Fatalf("intact chain: got %+v, %v", report, err)
+	}
+	head := report.Head
+
+	conn.Exec("UPDATE image_audit SET actor = 'mallory' WHERE seq = 2")
+	if report, _ := images.VerifyAudit(); len(report.Broken) != 1 || report.Broken[0].Seq != 2 {
+		t.Errorf("edited entry: got %+v", report.Broken)
+	}
+
+	conn.Exec("UPDATE image_audit SET actor = 'alice' WHERE seq = 2")
+	conn.Exec("DELETE FROM image_audit WHERE seq = 2")
+	if report, _ := images.VerifyAudit(); len(report.Broken) != 1 || report.Broken[0].Seq != 3 {
+		t.Errorf("removed entry: got %+v", report.Broken)
+	}
+
+	conn.Exec("DELETE FROM image_audit WHERE seq = 3")
+	if report, _ := images.VerifyAudit(); len(report.Broken) != 0 || report.Head == head {
+		t.Errorf("truncated log should only show up as a different head: got %+v", report)
+	}
+}
+
+// TestAuditChainIsKeyed checks that the chain can't be verified or rewritten
+// without the signing key
+func TestAuditChainIsKeyed(t *testing.T) {
+	conn, blobs := openDB(t)
+	images := registry.NewImageRepository(conn, testKeys, blobs)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+	images.Suspend("example_image", byAdmin)
+	images.Reinstate("example_image", byAdmin)
+
+	if log, _ := images.AuditLog(""); len(log) != 2 || log[0].KeyID != "k1" {
+		t.Fatalf("got %+v, want entries under k1", log)
+	}
+
+	wrongKey := registry.NewImageRepository(conn, &registry.Keyring{Active: "k1", Keys: map[string]string{"k1": "not the secret"}}, blobs)
+	if report, _ := wrongKey.VerifyAudit(); len(report.Broken) != 2 {
+		t.Errorf("wrong secret: got %+v, want every entry broken", report.Broken)
+	}
+
+	conn.Exec("UPDATE image_audit SET key_id = '' WHERE seq = 2")
+	if report, _ := images.VerifyAudit(); len(report.Broken) != 1 || report.Broken[0].Seq != 2 {
+		t.Errorf("downgraded entry: got %+v", report.Broken)
+	}
+	conn.Exec("UPDATE image_audit SET key_id = 'k9' WHERE seq = 2")
+	if report, _ := images.VerifyAudit(); len(report.Broken) != 1 || report.Broken[0].Seq != 2 {
+		t.Errorf("unknown key: got %+v", report.Broken)
+	}
+}
+
+// TestTransitionRefusesTamperedImage checks that neither a status change nor an
+// update re-signs a tampered row
+func TestTransitionRefusesTamperedImage(t *testing.T) {
+	conn, blobs := openDB(t)
+	images := registry.NewImageRepository(conn, testKeys, blobs)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+	conn.Exec("UPDATE image SET size = 1")
+
+	if _, err := images.Suspend("example_image", byAdmin); !errors.Is(err, registry.ErrVerificationFailed) {
+		t.Errorf("got %v, want ErrVerificationFailed", err)
+	}
+	if log, _ := images.AuditLog(""); len(log) != 0 {
+		t.Errorf("refused change was audited: %+v", log)
+	}
+	if _, err := images.Update("example_image", exampleInput()); !errors.Is(err, registry.ErrVerificationFailed) {
+		t.Errorf("update: got %v, want ErrVerificationFailed", err)
+	}
+}
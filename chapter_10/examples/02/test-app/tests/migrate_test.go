@@ -0,0 +1,195 @@
+package tests
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"test-app/cli"
+	"test-app/libs/blobstore"
+	"test-app/libs/db"
+	"test-app/utils"
+)
+
+func connect(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := db.Connect(filepath.Join(t.TempDir(), "images.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+var testMigrations = []db.Migration{
+	{Version: 2, Description: "add note table", Up: db.SQL("CREATE TABLE note (id INTEGER PRIMARY KEY, body TEXT)")},
+	{Version: 1, Description: "add tag table", Up: db.SQL("CREATE TABLE tag (id INTEGER PRIMARY KEY, name TEXT)")},
+}
+
+// TestMigrateInOrder checks that migrations run by version and only once
+func TestMigrateInOrder(t *testing.T) {
+	conn := connect(t)
+
+	pending, err := db.Migrate(conn, testMigrations, db.MigrateOptions{DryRun: true})
+	if err != nil || len(pending) != 2 || pending[0].Version != 1 {
+		t.Fatalf("dry run: got %v, %v", pending, err)
+	}
+	if version, _ := db.SchemaVersion(conn); version != 0 {
+		t.Fatalf("dry run changed the schema version to %d", version)
+	}
+
+	applied, err := db.Migrate(conn, testMigrations, db.MigrateOptions{})
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("got %v, %v", applied, err)
+	}
+	if applied, err = db.Migrate(conn, testMigrations, db.MigrateOptions{}); err != nil || len(applied) != 0 {
+		t.Errorf("second run: got %v, %v", applied, err)
+	}
+	if version, _ := db.SchemaVersion(conn); version != 2 {
+		t.Errorf("schema version %d, want 2", version)
+	}
+}
+
+// TestMigrateRefusesNewerSchema checks that a database from a newer program is left alone
+func TestMigrateRefusesNewerSchema(t *testing.T) {
+	conn := connect(t)
+	if _, err := db.Migrate(conn, testMigrations, db.MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Migrate(conn, testMigrations[1:], db.MigrateOptions{}); !errors.Is(err, db.ErrSchemaTooNew) {
+		t.Errorf("got %v, want ErrSchemaTooNew", err)
+	}
+}
+
+// TestMigrateRollsBackFailedStep checks that a failing migration leaves no trace
+func TestMigrateRollsBackFailedStep(t *testing.T) {
+	conn := connect(t)
+	broken := append(testMigrations, db.Migration{
+		Version:     3,
+		Description: "half applied",
+		Up:          db.SQL("CREATE TABLE half (id INTEGER)", "NOT SQL"),
+	})
+
+	applied, err := db.Migrate(conn, broken, db.MigrateOptions{})
+	if err == nil || len(applied) != 2 {
+		t.Fatalf("got %v, %v; want the first two applied and an error", applied, err)
+	}
+	if version, _ := db.SchemaVersion(conn); version != 2 {
+		t.Errorf("schema version %d, want 2", version)
+	}
+	var tables int
+	conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'half'").Scan(&tables)
+	if tables != 0 {
+		t.Error("table from the failed migration was kept")
+	}
+}
+
+// TestMigrateMovesContentsToBlobs upgrades a version 4 database and checks
+// that the contents end up in the blob store and IDs are not reused
+func TestMigrateMovesContentsToBlobs(t *testing.T) {
+	conn := connect(t)
+	blobs, err := blobstore.New(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Migrate(conn, db.Migrations(blobs)[:4], db.MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"kept", "deleted"} {
+		conn.Exec("INSERT INTO image (name, contents, sha256, hmac, team, team_owner) VALUES (?, 'héllo', ?, 'h', 't', 'o')",
+			name, utils.GenerateHash("héllo"))
+	}
+	conn.Exec("DELETE FROM image WHERE name = 'deleted'")
+
+	if _, err := db.Migrate(conn, db.Migrations(blobs), db.MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var size int64
+	if err := conn.QueryRow("SELECT size FROM image WHERE name = 'kept'").Scan(&size); err != nil || size != 6 {
+		t.Errorf("size: got %d, %v; want 6 bytes", size, err)
+	}
+	if stored, err := blobs.Stat(utils.GenerateHash("héllo")); err != nil || stored != 6 {
+		t.Errorf("blob: got %d, %v", stored, err)
+	}
+	var columns int
+	conn.QueryRow("SELECT COUNT(*) FROM pragma_table_info('image') WHERE name = 'contents'").Scan(&columns)
+	if columns != 0 {
+		t.Error("contents column was kept")
+	}
+
+	result, err := conn.Exec("INSERT INTO image (name, sha256, size, hmac, team, team_owner) VALUES ('new', 'x', 0, 'h', 't', 'o')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := result.LastInsertId(); id != 3 {
+		t.Errorf("new row got id %d, want 3", id)
+	}
+}
+
+// TestMigrateNumbersVersions checks that rows sharing a name are numbered in
+// creation order and that (name, version) is unique afterwards
+func TestMigrateNumbersVersions(t *testing.T) {
+	conn := connect(t)
+	blobs, err := blobstore.New(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Migrate(conn, db.Migrations(blobs)[:5], db.MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "a"} {
+		conn.Exec("INSERT INTO image (name, sha256, size, hmac, team, team_owner) VALUES (?, 'x', 0, 'h', 't', 'o')", name)
+	}
+
+	if _, err := db.Migrate(conn, db.Migrations(blobs), db.MigrateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := conn.Query("SELECT name, version FROM image ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for rows.Next() {
+		var name string
+		var version int
+		rows.Scan(&name, &version)
+		got = append(got, fmt.Sprintf("%s@%d", name, version))
+	}
+	rows.Close()
+	if strings.Join(got, " ") != "a@1 b@1 a@2" {
+		t.Errorf("got %v, want a@1 b@1 a@2", got)
+	}
+	if _, err := conn.Exec("INSERT INTO image (name, version, sha256, size, hmac, team, team_owner) VALUES ('b', 1, 'x', 0, 'h', 't', 'o')"); err == nil {
+		t.Error("duplicate (name, version) was accepted")
+	}
+}
+
+// TestMigrateDryRunCreatesNothing checks that `migrate --dry-run` lists the
+// steps without creating the blob store, and that a real run then applies them
+func TestMigrateDryRunCreatesNothing(t *testing.T) {
+	dir := t.TempDir()
+	cfg := cli.Config{DBPath: filepath.Join(dir, "images.db"), BlobDir: filepath.Join(dir, "blobs")}
+
+	var out strings.Builder
+	if code := cli.Run(cfg, []string{"migrate", "--dry-run"}, &out, io.Discard); code != cli.ExitOK {
+		t.Fatalf("dry run: exit %d", code)
+	}
+	if !strings.Contains(out.String(), "Pending migration 1:") {
+		t.Errorf("dry run output:\n%s", out.String())
+	}
+	if _, err := os.Stat(cfg.BlobDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("dry run created the blob store: %v", err)
+	}
+
+	if code := cli.Run(cfg, []string{"migrate"}, io.Discard, io.Discard); code != cli.ExitOK {
+		t.Fatalf("migrate: exit %d", code)
+	}
+	if _, err := os.Stat(cfg.BlobDir); err != nil {
+		t.Errorf("migrate did not create the blob store: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"test-app/libs/blobstore"
+	"test-app/libs/db"
+	"test-app/libs/registry"
+	"test-app/utils"
+)
+
+const secretKey = "test-secret"
+
+var testKeys = &registry.Keyring{Active: "k1", Keys: map[string]string{"k1": secretKey}}
+
+// openDB opens a fresh, migrated registry database and blob store in a temporary directory
+func openDB(t *testing.T) (*sql.DB, *blobstore.Store) {
+	t.Helper()
+	dir := t.TempDir()
+	blobs, err := blobstore.New(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.Open(filepath.Join(dir, "images.db"), blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, blobs
+}
+
+// newRepository opens a fresh registry in a temporary SQLite file
+func newRepository(t *testing.T) *registry.ImageRepository {
+	t.Helper()
+	conn, blobs := openDB(t)
+	return registry.NewImageRepository(conn, testKeys, blobs)
+}
+
+func exampleInput() registry.ImageInput {
+	return registry.ImageInput{Name: "example_image", Contents: "example_data", Team: "team_a", TeamOwner: "owner_a"}
+}
+
+// TestCreateAndGet checks that a created image reads back signed and complete
+func TestCreateAndGet(t *testing.T) {
+	images := newRepository(t)
+
+	created, err := images.Create(exampleInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == 0 || created.Status != registry.StatusActive {
+		t.Errorf("unexpected image %+v", created)
+	}
+	if created.SHA256 != utils.GenerateHash("example_data") || created.HMAC != utils.GenerateHMAC(secretKey, "example_data") {
+		t.Errorf("hashes not computed: %+v", created)
+	}
+
+	got, err := images.Get("example_image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != created {
+		t.Errorf("Get returned %+v, want %+v", got, created)
+	}
+}
+
+// TestNotFound checks that missing images are reported with ErrNotFound
+func TestNotFound(t *testing.T) {
+	images := newRepository(t)
+
+	if _, err := images.Get("missing"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Get: got %v, want ErrNotFound", err)
+	}
+	if _, err := images.Update("missing", exampleInput()); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Update: got %v, want ErrNotFound", err)
+	}
+	if err := images.Delete("missing"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+// TestUpdateAndDelete checks that an update re-signs the image and delete removes it
+func TestUpdateAndDelete(t *testing.T) {
+	images := newRepository(t)
+	created, err := images.Create(exampleInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := exampleInput()
+	in.Contents = "new_data"
+	in.Team = "team_b"
+	updated, err := images.Update("example_image", in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.SHA256 == created.SHA256 || updated.StatusSignature == created.StatusSignature || updated.Team != "team_b" {
+		t.Errorf("update not applied: %+v", updated)
+	}
+	if got, _ := images.Get("example_image"); got != updated {
+		t.Errorf("Get after Update: got %+v, want %+v", got, updated)
+	}
+	if contents, err := images.ReadContents("example_image"); err != nil || contents != "new_data" {
+		t.Errorf("contents after Update: got %q, %v", contents, err)
+	}
+	if first, err := images.GetVersion("example_image", 1); err != nil || first != created {
+		t.Errorf("version 1 after Update: got %+v, %v", first, err)
+	}
+
+	if err := images.Delete("example_image"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := images.Get("example_image"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Get after Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+// TestVersions checks that names are unique and each Update adds a version
+func TestVersions(t *testing.T) {
+	images := newRepository(t)
+	created, err := images.Create(exampleInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Version != 1 {
+		t.Errorf("Create: got version %d, want 1", created.Version)
+	}
+	if _, err := images.Create(exampleInput()); !errors.Is(err, registry.ErrAlreadyExists) {
+		t.Errorf("second Create: got %v, want ErrAlreadyExists", err)
+	}
+
+	for _, contents := range []string{"v2", "v3"} {
+		in := exampleInput()
+		in.Contents = contents
+		if _, err := images.Update("example_image", in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err := images.History("example_image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 || history[0] != created {
+		t.Fatalf("history: got %+v", history)
+	}
+	for i, img := range history {
+		if img.Version != i+1 || !images.VerifyImage(img).OK() {
+			t.Errorf("history[%d]: %+v", i, img)
+		}
+	}
+	if latest, _ := images.Get("example_image"); latest != history[2] || latest.SHA256 != utils.GenerateHash("v3") {
+		t.Errorf("Get: got %+v, want version 3", latest)
+	}
+	if _, err := images.GetVersion("example_image", 4); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("missing version: got %v, want ErrNotFound", err)
+	}
+	if _, err := images.History("other"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("missing history: got %v, want ErrNotFound", err)
+	}
+
+	// The version is signed, so an old row can't be renumbered to pass as the latest.
+	old := history[0]
+	old.Version = 4
+	if result := images.VerifyImage(old); result.OK() {
+		t.Error("renumbered version verified")
+	}
+
+	if err := images.Delete("example_image"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := images.History("example_image"); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Delete kept versions: got %v", err)
+	}
+}
+
+// TestInvalidInput checks the required fields and the status values
+func TestInvalidInput(t *testing.T) {
+	images := newRepository(t)
+
+	in := exampleInput()
+	in.Team = ""
+	if _, err := images.Create(in); !errors.Is(err, registry.ErrMissingField) {
+		t.Errorf("missing team: got %v, want ErrMissingField", err)
+	}
+	in = exampleInput()
+	in.Status = "deleted"
+	if _, err := images.Create(in); !errors.Is(err, registry.ErrInvalidStatus) {
+		t.Errorf("bad status: got %v, want ErrInvalidStatus", err)
+	}
+}
+
+// TestExportCSV checks that a NULL status_url is exported as an empty field
+func TestExportCSV(t *testing.T) {
+	images := newRepository(t)
+	if _, err := images.Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := images.ExportCSV(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want header and one row:\n%s", len(lines), out.String())
+	}
+	if strings.Contains(lines[1], "<nil>") || !strings.HasSuffix(lines[1], ",,4,k1,1") {
+		t.Errorf("status_url not exported as empty: %s", lines[1])
+	}
+}
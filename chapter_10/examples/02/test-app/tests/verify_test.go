@@ -0,0 +1,176 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"test-app/cli"
+	"test-app/libs/blobstore"
+	"test-app/libs/db"
+	"test-app/libs/registry"
+	"test-app/utils"
+)
+
+// TestVerifyAll tampers with stored rows and blobs and checks which fields are reported
+func TestVerifyAll(t *testing.T) {
+	conn, blobs := openDB(t)
+	images := registry.NewImageRepository(conn, testKeys, blobs)
+	paths := make(map[string]string)
+	for _, name := range []string{"intact", "contents_changed", "blob_missing", "status_changed"} {
+		in := exampleInput()
+		in.Name, in.Contents = name, "contents of "+name
+		img, err := images.Create(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[name], _ = blobs.Path(img.SHA256)
+	}
+
+	os.WriteFile(paths["contents_changed"], []byte("evil"), 0o600)
+	os.Remove(paths["blob_missing"])
+	conn.Exec("UPDATE image SET status = 'revoked' WHERE name = 'status_changed'")
+
+	report, err := images.VerifyAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 4 {
+		t.Errorf("checked %d images, want 4", report.Checked)
+	}
+	want := map[string][]string{
+		"contents_changed": {registry.FieldSHA256, registry.FieldSize, registry.FieldHMAC},
+		"blob_missing":     {registry.FieldBlob, registry.FieldHMAC},
+		"status_changed":   {registry.FieldStatusSignature},
+	}
+	got := make(map[string][]string)
+	for _, result := range report.Tampered {
+		got[result.Name] = result.Failed
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tampered fields: got %v, want %v", got, want)
+	}
+
+	if _, err := images.ReadContents("contents_changed"); !errors.Is(err, blobstore.ErrDigestMismatch) {
+		t.Errorf("reading a changed blob: got %v, want ErrDigestMismatch", err)
+	}
+}
+
+// TestVerifyCommandExitCode checks that the verify command exits with
+// ExitTampered once a row has been changed behind the registry's back
+func TestVerifyCommandExitCode(t *testing.T) {
+	dir := t.TempDir()
+	cfg := cli.Config{DBPath: filepath.Join(dir, "images.db"), BlobDir: filepath.Join(dir, "blobs"), Keys: testKeys}
+	blobs, err := blobstore.New(cfg.BlobDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.Open(cfg.DBPath, blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := registry.NewImageRepository(conn, testKeys, blobs).Create(exampleInput()); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := cli.Run(cfg, []string{"verify"}, io.Discard, io.Discard); code != cli.ExitOK {
+		t.Fatalf("intact registry: exit %d", code)
+	}
+
+	conn.Exec("UPDATE image SET team = 'team_x'")
+	var out strings.Builder
+	if code := cli.Run(cfg, []string{"verify"}, &out, io.Discard); code != cli.ExitTampered {
+		t.Errorf("tampered row: exit %d, want %d", code, cli.ExitTampered)
+	}
+	if !strings.Contains(out.String(), "TAMPERED image 1 (example_image)") {
+		t.Errorf("tampered row not reported:\n%s", out.String())
+	}
+}
+
+// TestVerifyWrongKey checks that a different secret fails the keyed fields
+// only, and a missing key ID is reported as such
+func TestVerifyWrongKey(t *testing.T) {
+	conn, blobs := openDB(t)
+	img, err := registry.NewImageRepository(conn, testKeys, blobs).Create(exampleInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := &registry.Keyring{Active: "k1", Keys: map[string]string{"k1": "other-secret"}}
+	result := registry.NewImageRepository(conn, changed, blobs).VerifyImage(img)
+	if want := []string{registry.FieldHMAC, registry.FieldStatusSignature}; !reflect.DeepEqual(result.Failed, want) {
+		t.Errorf("changed secret: got %v, want %v", result.Failed, want)
+	}
+
+	missing := &registry.Keyring{Active: "k2", Keys: map[string]string{"k2": secretKey}}
+	result = registry.NewImageRepository(conn, missing, blobs).VerifyImage(img)
+	if want := []string{registry.FieldKeyID, registry.FieldHMAC, registry.FieldStatusSignature}; !reflect.DeepEqual(result.Failed, want) {
+		t.Errorf("missing key: got %v, want %v", result.Failed, want)
+	}
+}
+
+// TestSigningInputIsUnambiguous checks that moving bytes between fields
+// changes the version 2 input, which version 1 did not
+func TestSigningInputIsUnambiguous(t *testing.T) {
+	a := registry.Image{Name: "ab", Team: "t", TeamOwner: "o", Status: "active"}
+	b := registry.Image{Name: "a", Team: "t", TeamOwner: "o", Status: "active"}
+
+	v1a, _ := registry.SigningInput(registry.SignatureV1, a, "c")
+	v1b, _ := registry.SigningInput(registry.SignatureV1, b, "bc")
+	if string(v1a) != string(v1b) {
+		t.Fatal("expected the version 1 inputs to collide")
+	}
+	v2a, _ := registry.SigningInput(registry.SignatureV2, a, "c")
+	v2b, _ := registry.SigningInput(registry.SignatureV2, b, "bc")
+	if string(v2a) == string(v2b) {
+		t.Error("version 2 inputs collide")
+	}
+	if _, err := registry.SigningInput(99, a, ""); !errors.Is(err, registry.ErrUnknownSignatureVersion) {
+		t.Errorf("got %v, want ErrUnknownSignatureVersion", err)
+	}
+}
+
+// TestResign moves an old-style row to the current encoding and leaves tampered rows alone
+func TestResign(t *testing.T) {
+	conn, blobs := openDB(t)
+	images := registry.NewImageRepository(conn, testKeys, blobs)
+	for _, name := range []string{"legacy", "tampered"} {
+		in := exampleInput()
+		in.Name = name
+		img, err := images.Create(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Rewrite the row the way the old createImage signed it.
+		input, _ := registry.SigningInput(registry.SignatureV1, img, in.Contents)
+		conn.Exec("UPDATE image SET status_signature = ?, signature_version = 1 WHERE id = ?",
+			utils.GenerateHMAC(secretKey, string(input)), img.ID)
+	}
+	conn.Exec("UPDATE image SET team = 'team_x' WHERE name = 'tampered'")
+
+	report, err := images.VerifyAll()
+	if err != nil || report.Legacy != 1 || len(report.Tampered) != 1 {
+		t.Fatalf("before resign: got %+v, %v; want one legacy and one tampered row", report, err)
+	}
+
+	resigned, err := images.Resign(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resigned.Resigned != 1 || len(resigned.Skipped) != 1 || resigned.Skipped[0].Name != "tampered" {
+		t.Errorf("got %+v, want legacy re-signed and tampered skipped", resigned)
+	}
+
+	img, _ := images.Get("legacy")
+	if img.SignatureVersion != registry.CurrentSignatureVersion || !images.VerifyImage(img).OK() {
+		t.Errorf("legacy row after resign: %+v", img)
+	}
+	if report, _ := images.VerifyAll(); report.Legacy != 0 || len(report.Tampered) != 1 {
+		t.Errorf("after resign: got %+v", report)
+	}
+}
@@ -1,4 +1,4 @@
 module test-nist
 
-go 1.23.3
+go 1.24
 
@@ -0,0 +1,103 @@
+package libs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+)
+
+func init() {
+	Register(CaseHarness(checkAESCase("ECB")), "ACVP-AES-ECB", "AES-ECB")
+	Register(CaseHarness(checkAESCase("CBC")), "ACVP-AES-CBC", "AES-CBC")
+	Register(CaseHarness(checkAESCase("CTR")), "ACVP-AES-CTR", "AES-CTR")
+}
+
+// AESGroup shares its parameters between AES-ECB, CBC or CTR test cases
+type AESGroup struct {
+	TestType  string `json:"testType"` // only AFT is run, MCT and CTR are skipped
+	Direction string `json:"direction"`
+	KeyLen    int    `json:"keyLen"`
+}
+
+// AESCase is one AES-ECB, CBC or CTR test vector
+type AESCase struct {
+	Key        string `json:"key"`
+	IV         string `json:"iv"` // the initial counter block for CTR
+	PT         string `json:"pt"`
+	CT         string `json:"ct"`
+	PayloadLen int    `json:"payloadLen"` // bits, only set by CTR
+}
+
+// checkAESCase returns the check for the block cipher mode ECB, CBC or CTR
+func checkAESCase(mode string) func(group AESGroup, t AESCase) (map[string]any, error) {
+	return func(group AESGroup, t AESCase) (map[string]any, error) {
+		if group.TestType != "AFT" {
+			return nil, fmt.Errorf("%w: AES-%s %s tests", ErrNotSupported, mode, group.TestType)
+		}
+		if t.PayloadLen%8 != 0 {
+			return nil, fmt.Errorf("%w: payloadLen %d is not whole bytes", ErrNotSupported, t.PayloadLen)
+		}
+		var d hexDecoder
+		key := d.decode("key", t.Key)
+		iv := d.decode("IV", t.IV)
+		pt := d.decode("plaintext", t.PT)
+		ct := d.decode("ciphertext", t.CT)
+		if d.err != nil {
+			return nil, d.err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+
+		encrypt := group.Direction == "encrypt"
+		in, want, field := ct, pt, "pt"
+		if encrypt {
+			in, want, field = pt, ct, "ct"
+		}
+		out, err := cryptAES(mode, block, iv, in, encrypt)
+		if err != nil {
+			return nil, fmt.Errorf("%s failed: %w", group.Direction, err)
+		}
+		output := map[string]any{field: hex.EncodeToString(out)}
+		if !compareBytes(out, want) {
+			return output, fmt.Errorf("%s mismatch: expected %X, got %X", field, want, out)
+		}
+		return output, nil
+	}
+}
+
+// cryptAES encrypts or decrypts in with block in the given mode
+func cryptAES(mode string, block cipher.Block, iv, in []byte, encrypt bool) ([]byte, error) {
+	if mode != "CTR" && len(in)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("input is %d bytes, not whole blocks", len(in))
+	}
+	if mode != "ECB" && len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("IV is %d bytes, want %d", len(iv), aes.BlockSize)
+	}
+
+	out := make([]byte, len(in))
+	switch mode {
+	case "ECB":
+		for i := 0; i < len(in); i += aes.BlockSize {
+			if encrypt {
+				block.Encrypt(out[i:], in[i:])
+			} else {
+				block.Decrypt(out[i:], in[i:])
+			}
+		}
+	case "CBC":
+		if encrypt {
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, in)
+		} else {
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, in)
+		}
+	case "CTR":
+		// CTR decrypts by encrypting again
+		cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	default:
+		return nil, fmt.Errorf("unknown AES mode %s", mode)
+	}
+	return out, nil
+}
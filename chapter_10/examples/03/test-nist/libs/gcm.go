@@ -0,0 +1,200 @@
+package libs
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrAuthentication   = errors.New("message authentication failed")
+	ErrInvalidTagLength = errors.New("invalid GCM tag length, want 4, 8 or 12 to 16 bytes")
+)
+
+func init() {
+	Register(CaseHarness(checkGCMCase), "ACVP-AES-GCM", "AES-GCM")
+}
+
+// GCMGroup shares its parameters between its test cases
+type GCMGroup struct {
+	Direction string `json:"direction"`
+	KeyLen    int    `json:"keyLen"`
+	IVLen     int    `json:"ivLen"`
+	PTLen     int    `json:"payloadLen"`
+	AADLen    int    `json:"aadLen"`
+	TagLen    int    `json:"tagLen"`
+}
+
+// GCMCase is one AES-GCM test vector
+type GCMCase struct {
+	Key string `json:"key"`
+	IV  string `json:"iv"`
+	PT  string `json:"pt"`
+	AAD string `json:"aad"`
+	CT  string `json:"ct"`
+	Tag string `json:"tag"`
+	// TestPass is false for a decrypt case whose tag must be rejected; the
+	// answers leave it out for cases that decrypt
+	TestPass *bool `json:"testPassed"`
+}
+
+// ExpectFailure reports whether the case must fail authentication
+func (t GCMCase) ExpectFailure() bool {
+	return t.TestPass != nil && !*t.TestPass
+}
+
+// checkGCMCase runs one AES-GCM test case. It returns the values computed
+// for the ACVP response, and why the case failed or nil.
+func checkGCMCase(group GCMGroup, t GCMCase) (map[string]any, error) {
+	key, err := hex.DecodeString(t.Key)
+	if err != nil {
+		return nil, fmt.Errorf("decoding key: %w", err)
+	}
+	iv, err := hex.DecodeString(t.IV)
+	if err != nil {
+		return nil, fmt.Errorf("decoding IV: %w", err)
+	}
+	pt, err := hex.DecodeString(t.PT)
+	if err != nil {
+		return nil, fmt.Errorf("decoding plaintext: %w", err)
+	}
+	aad, err := hex.DecodeString(t.AAD)
+	if err != nil {
+		return nil, fmt.Errorf("decoding AAD: %w", err)
+	}
+	expectedCT, err := hex.DecodeString(t.CT)
+	if err != nil {
+		return nil, fmt.Errorf("decoding ciphertext: %w", err)
+	}
+	expectedTag, err := hex.DecodeString(t.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("decoding tag: %w", err)
+	}
+
+	if group.IVLen != 0 && len(iv)*8 != group.IVLen {
+		return nil, fmt.Errorf("IV is %d bits, group says ivLen %d", len(iv)*8, group.IVLen)
+	}
+	if group.TagLen%8 != 0 {
+		return nil, fmt.Errorf("%w: tagLen %d is not whole bytes", ErrInvalidTagLength, group.TagLen)
+	}
+
+	if group.Direction == "encrypt" {
+		// Create a custom GCM with the specified tag length
+		ct, tag, err := EncryptAESGCMWithParams(key, iv, pt, aad, group.TagLen/8)
+		if err != nil {
+			return nil, fmt.Errorf("encryption failed: %w", err)
+		}
+		output := map[string]any{"ct": hex.EncodeToString(ct), "tag": hex.EncodeToString(tag)}
+		if !compareBytes(ct, expectedCT) {
+			return output, fmt.Errorf("ciphertext mismatch: expected %X, got %X", expectedCT, ct)
+		}
+		if !compareBytes(tag, expectedTag) {
+			return output, fmt.Errorf("tag mismatch: expected %X, got %X", expectedTag, tag)
+		}
+		return output, nil
+	}
+
+	// decrypt: an expected-failure case passes only if the tag is rejected;
+	// any other error (bad key or tag length) is still a failure.
+	decryptedPT, err := DecryptAESGCMWithParams(key, iv, expectedCT, expectedTag, aad, group.TagLen/8)
+	switch {
+	case errors.Is(err, ErrAuthentication) && t.ExpectFailure():
+		return map[string]any{"testPassed": false}, nil
+	case errors.Is(err, ErrAuthentication):
+		return map[string]any{"testPassed": false}, fmt.Errorf("tag rejected, expected plaintext %X", pt)
+	case err != nil:
+		return nil, fmt.Errorf("decryption error: %w", err)
+	}
+	output := map[string]any{"pt": hex.EncodeToString(decryptedPT)}
+	if t.ExpectFailure() {
+		return output, fmt.Errorf("tag accepted, expected authentication to fail")
+	}
+	if !compareBytes(decryptedPT, pt) {
+		return output, fmt.Errorf("plaintext mismatch: expected %X, got %X", pt, decryptedPT)
+	}
+	return output, nil
+}
+
+// validTagLen reports whether tagLen bytes is a GCM tag size allowed by
+// SP 800-38D: 128, 120, 112, 104, 96, 64 or 32 bits
+func validTagLen(tagLen int) bool {
+	switch tagLen {
+	case 16, 15, 14, 13, 12, 8, 4:
+		return true
+	}
+	return false
+}
+
+// newGCM returns AES-GCM with a nonce of len(iv) bytes. IVs other than 96
+// bits are hashed into the initial counter, as SP 800-38D specifies.
+func newGCM(key, iv []byte) (cipher.AEAD, error) {
+	if len(iv) == 0 {
+		return nil, fmt.Errorf("IV cannot be empty")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// EncryptAESGCMWithParams returns the ciphertext and the tag truncated to tagLen bytes
+func EncryptAESGCMWithParams(key, iv, plaintext, aad []byte, tagLen int) ([]byte, []byte, error) {
+	if !validTagLen(tagLen) {
+		return nil, nil, fmt.Errorf("%w: %d bytes", ErrInvalidTagLength, tagLen)
+	}
+	gcm, err := newGCM(key, iv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Seal appends the full 16-byte tag; a shorter tag is its prefix.
+	sealed := gcm.Seal(nil, iv, plaintext, aad)
+	ciphertext := sealed[:len(plaintext)]
+	tag := sealed[len(plaintext):][:tagLen]
+	return ciphertext, tag, nil
+}
+
+// DecryptAESGCMWithParams checks a tag of tagLen bytes and returns the
+// plaintext, or an error wrapping ErrAuthentication if the tag is wrong
+func DecryptAESGCMWithParams(key, iv, ciphertext, tag, aad []byte, tagLen int) ([]byte, error) {
+	if !validTagLen(tagLen) {
+		return nil, fmt.Errorf("%w: %d bytes", ErrInvalidTagLength, tagLen)
+	}
+	if len(tag) != tagLen {
+		return nil, fmt.Errorf("%w: got a %d-byte tag, want %d", ErrInvalidTagLength, len(tag), tagLen)
+	}
+	gcm, err := newGCM(key, iv)
+	if err != nil {
+		return nil, err
+	}
+
+	if tagLen == gcm.Overhead() {
+		// Copy so the append can't write into the caller's array.
+		sealed := append(append([]byte(nil), ciphertext...), tag...)
+		plaintext, err := gcm.Open(nil, iv, sealed, aad)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAuthentication, err)
+		}
+		return plaintext, nil
+	}
+
+	// Go only opens full tags when the nonce size is custom, and not 32 or 64
+	// bits at all. GCM encrypts by XOR with a keystream, so sealing the
+	// ciphertext recovers the plaintext, and sealing that gives the full tag
+	// to compare the truncated one with. The plaintext is only returned once
+	// the tag matches.
+	plaintext := gcm.Seal(nil, iv, ciphertext, aad)[:len(ciphertext)]
+	full := gcm.Seal(nil, iv, plaintext, aad)
+	if subtle.ConstantTimeCompare(full[len(ciphertext):][:tagLen], tag) != 1 {
+		return nil, ErrAuthentication
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,123 @@
+package libs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrNotSupported marks a test case the harness can't run, e.g. a Monte
+// Carlo test it does not implement; the case is reported as skipped
+var ErrNotSupported = errors.New("not supported")
+
+// Case is one test case of a group. Check returns the values computed for
+// the ACVP response, and why the case failed or nil.
+type Case struct {
+	ID    int
+	Check func() (map[string]any, error)
+}
+
+// Harness decodes one test group of a vector set into its report header
+// (tgId, test type, direction) and its cases. The runner calls the checks,
+// possibly from several goroutines at once.
+type Harness func(test *NISTTest, group json.RawMessage) (GroupReport, []Case)
+
+// harnesses maps NISTTest.Name to the harness for that algorithm
+var harnesses = map[string]Harness{}
+
+// Register makes h the harness for vector sets whose Name is one of names.
+// The harnesses in this package register themselves in init.
+func Register(h Harness, names ...string) {
+	for _, name := range names {
+		harnesses[name] = h
+	}
+}
+
+// HarnessFor returns the harness registered for the algorithm and mode of test, or nil
+func HarnessFor(test *NISTTest) Harness {
+	return harnesses[test.Name()]
+}
+
+// Algorithms returns the names that have a harness, sorted
+func Algorithms() []string {
+	names := make([]string, 0, len(harnesses))
+	for name := range harnesses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// groupHeader holds the fields every test group has
+type groupHeader struct {
+	ID        int               `json:"tgId"`
+	TestType  string            `json:"testType"`
+	Direction string            `json:"direction"`
+	Tests     []json.RawMessage `json:"tests"`
+}
+
+// CaseHarness returns a Harness that decodes the group into G and each case
+// into C, and checks a case with check. check returns the values computed
+// for the ACVP response, and why the case failed or nil; an error wrapping
+// ErrNotSupported marks the case skipped. check must not modify the group.
+func CaseHarness[G, C any](check func(group G, c C) (map[string]any, error)) Harness {
+	return func(test *NISTTest, raw json.RawMessage) (GroupReport, []Case) {
+		var header groupHeader
+		if err := json.Unmarshal(raw, &header); err != nil {
+			return GroupReport{}, []Case{{Check: func() (map[string]any, error) {
+				return nil, fmt.Errorf("decoding test group: %w", err)
+			}}}
+		}
+		result := GroupReport{ID: header.ID, TestType: header.TestType, Direction: header.Direction}
+
+		var group G
+		groupErr := json.Unmarshal(raw, &group)
+		cases := make([]Case, len(header.Tests))
+		for i, rawCase := range header.Tests {
+			var id struct {
+				ID int `json:"tcId"`
+			}
+			json.Unmarshal(rawCase, &id)
+			cases[i] = Case{ID: id.ID, Check: func() (map[string]any, error) {
+				if groupErr != nil {
+					return nil, fmt.Errorf("decoding test group: %w", groupErr)
+				}
+				var c C
+				if err := json.Unmarshal(rawCase, &c); err != nil {
+					return nil, fmt.Errorf("decoding test case: %w", err)
+				}
+				return check(group, c)
+			}}
+		}
+		return result, cases
+	}
+}
+
+func caseResult(id int, output map[string]any, err error) CaseResult {
+	switch {
+	case err == nil:
+		return CaseResult{ID: id, Passed: true, Output: output}
+	case errors.Is(err, ErrNotSupported):
+		return CaseResult{ID: id, Skipped: true, Reason: err.Error()}
+	}
+	return CaseResult{ID: id, Reason: err.Error(), Output: output}
+}
+
+// hexDecoder decodes hex fields one after another and keeps the first
+// error, naming the field, so a check can test it once at the end
+type hexDecoder struct {
+	err error
+}
+
+func (d *hexDecoder) decode(name, s string) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		d.err = fmt.Errorf("decoding %s: %w", name, err)
+	}
+	return b
+}
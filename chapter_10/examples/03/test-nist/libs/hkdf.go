@@ -0,0 +1,138 @@
+package libs
+
+import (
+	"crypto/hkdf"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+func init() {
+	Register(CaseHarness(checkHKDFCase), "KDA-HKDF")
+}
+
+// HKDFGroup shares the KDF configuration between KDA-HKDF test cases
+type HKDFGroup struct {
+	TestType string     `json:"testType"` // AFT computes the key, VAL checks one
+	Config   HKDFConfig `json:"kdfConfiguration"`
+}
+
+// HKDFConfig says how the fixed info is built and which hash HKDF uses
+type HKDFConfig struct {
+	FixedInfoPattern  string `json:"fixedInfoPattern"`  // e.g. uPartyInfo||vPartyInfo||l
+	FixedInfoEncoding string `json:"fixedInfoEncoding"` // only concatenation is supported
+	HMACAlg           string `json:"hmacAlg"`           // e.g. SHA2-256
+	L                 int    `json:"l"`                 // derived key length in bits
+}
+
+// HKDFCase is one KDA-HKDF test vector. For VAL, DKM is the key to check
+// and TestPassed whether it is right.
+type HKDFCase struct {
+	Params     HKDFParameter `json:"kdfParameter"`
+	PartyU     HKDFParty     `json:"fixedInfoPartyU"`
+	PartyV     HKDFParty     `json:"fixedInfoPartyV"`
+	DKM        string        `json:"dkm"`
+	TestPassed *bool         `json:"testPassed"`
+}
+
+// HKDFParameter holds the inputs to the key derivation
+type HKDFParameter struct {
+	Salt        string `json:"salt"`
+	Z           string `json:"z"` // the shared secret
+	L           int    `json:"l"` // overrides the group's l when set
+	AlgorithmID string `json:"algorithmId"`
+	Label       string `json:"label"`
+	Context     string `json:"context"`
+}
+
+// HKDFParty is the fixed info contributed by one party
+type HKDFParty struct {
+	PartyID       string `json:"partyId"`
+	EphemeralData string `json:"ephemeralData"`
+}
+
+func checkHKDFCase(group HKDFGroup, t HKDFCase) (map[string]any, error) {
+	config := group.Config
+	newHash, ok := hashes[config.HMACAlg]
+	if !ok {
+		return nil, fmt.Errorf("%w: hmacAlg %q", ErrNotSupported, config.HMACAlg)
+	}
+	if config.FixedInfoEncoding != "" && config.FixedInfoEncoding != "concatenation" {
+		return nil, fmt.Errorf("%w: fixedInfoEncoding %q", ErrNotSupported, config.FixedInfoEncoding)
+	}
+	l := config.L
+	if t.Params.L != 0 {
+		l = t.Params.L
+	}
+	if l%8 != 0 || l <= 0 {
+		return nil, fmt.Errorf("%w: l %d is not whole bytes", ErrNotSupported, l)
+	}
+
+	var d hexDecoder
+	salt := d.decode("salt", t.Params.Salt)
+	z := d.decode("z", t.Params.Z)
+	dkm := d.decode("dkm", t.DKM)
+	if d.err != nil {
+		return nil, d.err
+	}
+	info, err := fixedInfo(config.FixedInfoPattern, l, t)
+	if err != nil {
+		return nil, err
+	}
+	derived, err := hkdf.Key(newHash, z, salt, string(info), l/8)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	switch group.TestType {
+	case "AFT":
+		output := map[string]any{"dkm": hex.EncodeToString(derived)}
+		if !compareBytes(derived, dkm) {
+			return output, fmt.Errorf("dkm mismatch: expected %X, got %X", dkm, derived)
+		}
+		return output, nil
+	case "VAL":
+		if t.TestPassed == nil {
+			return nil, fmt.Errorf("no testPassed to validate against")
+		}
+		matches := compareBytes(derived, dkm)
+		output := map[string]any{"testPassed": matches}
+		if matches != *t.TestPassed {
+			return output, fmt.Errorf("dkm check gave %t, expected %t", matches, *t.TestPassed)
+		}
+		return output, nil
+	}
+	return nil, fmt.Errorf("%w: %s tests", ErrNotSupported, group.TestType)
+}
+
+// fixedInfo concatenates the fields named by pattern, e.g.
+// uPartyInfo||vPartyInfo||l, where l is the key length in bits as 32 bits
+func fixedInfo(pattern string, l int, t HKDFCase) ([]byte, error) {
+	var d hexDecoder
+	var info []byte
+	for _, token := range strings.Split(pattern, "||") {
+		switch {
+		case token == "uPartyInfo":
+			info = append(info, d.decode("partyU partyId", t.PartyU.PartyID)...)
+			info = append(info, d.decode("partyU ephemeralData", t.PartyU.EphemeralData)...)
+		case token == "vPartyInfo":
+			info = append(info, d.decode("partyV partyId", t.PartyV.PartyID)...)
+			info = append(info, d.decode("partyV ephemeralData", t.PartyV.EphemeralData)...)
+		case token == "l":
+			info = binary.BigEndian.AppendUint32(info, uint32(l))
+		case token == "algorithmId":
+			info = append(info, d.decode("algorithmId", t.Params.AlgorithmID)...)
+		case token == "label":
+			info = append(info, d.decode("label", t.Params.Label)...)
+		case token == "context":
+			info = append(info, d.decode("context", t.Params.Context)...)
+		case strings.HasPrefix(token, "literal[") && strings.HasSuffix(token, "]"):
+			info = append(info, d.decode(token, token[len("literal["):len(token)-1])...)
+		case token == "": // an empty pattern
+		default:
+			return nil, fmt.Errorf("%w: fixedInfoPattern field %q", ErrNotSupported, token)
+		}
+	}
+	return info, d.err
+}
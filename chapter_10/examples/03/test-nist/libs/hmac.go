@@ -0,0 +1,53 @@
+package libs
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+func init() {
+	for name, newHash := range hashes {
+		Register(CaseHarness(checkHMACCase(newHash)), "HMAC-"+name)
+	}
+}
+
+// HMACGroup shares its parameters between HMAC test cases
+type HMACGroup struct {
+	MacLen int `json:"macLen"` // bits, the MAC is truncated to it
+}
+
+// HMACCase is one HMAC test vector
+type HMACCase struct {
+	Key string `json:"key"`
+	Msg string `json:"msg"`
+	Mac string `json:"mac"`
+}
+
+// checkHMACCase returns the check for HMAC with newHash
+func checkHMACCase(newHash func() hash.Hash) func(group HMACGroup, t HMACCase) (map[string]any, error) {
+	return func(group HMACGroup, t HMACCase) (map[string]any, error) {
+		var d hexDecoder
+		key := d.decode("key", t.Key)
+		msg := d.decode("msg", t.Msg)
+		expected := d.decode("mac", t.Mac)
+		if d.err != nil {
+			return nil, d.err
+		}
+
+		mac := hmac.New(newHash, key)
+		mac.Write(msg)
+		sum := mac.Sum(nil)
+		if group.MacLen%8 != 0 || group.MacLen <= 0 || group.MacLen/8 > len(sum) {
+			return nil, fmt.Errorf("macLen %d is not a whole number of bytes up to %d", group.MacLen, len(sum)*8)
+		}
+		sum = sum[:group.MacLen/8]
+
+		output := map[string]any{"mac": hex.EncodeToString(sum)}
+		if !compareBytes(sum, expected) {
+			return output, fmt.Errorf("MAC mismatch: expected %X, got %X", expected, sum)
+		}
+		return output, nil
+	}
+}
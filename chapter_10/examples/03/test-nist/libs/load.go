@@ -0,0 +1,237 @@
+package libs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// File names used by the ACVP-Server gen-val/json-files layout. A directory
+// holds the prompt sent to the client, the answers, and the two merged.
+const (
+	promptFile             = "prompt.json"
+	expectedResultsFile    = "expectedResults.json"
+	internalProjectionFile = "internalProjection.json"
+)
+
+var (
+	ErrNoTestGroups = errors.New("no testGroups, not a vector set")
+	ErrNoVectorSets = errors.New("no vector sets found")
+)
+
+// ParseNISTTest decodes one vector set. It accepts a bare vector set object
+// and the ACVP exchange form, an array whose first element holds acvVersion.
+func ParseNISTTest(data []byte) (*NISTTest, error) {
+	raw, err := vectorSet(data)
+	if err != nil {
+		return nil, err
+	}
+	var test NISTTest
+	if err := json.Unmarshal(raw, &test); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return &test, nil
+}
+
+// LoadNISTTest reads a vector set from a file. For a prompt.json the answers
+// are taken from the expectedResults.json next to it.
+func LoadNISTTest(path string) (*NISTTest, error) {
+	var data []byte
+	var err error
+	if filepath.Base(path) == promptFile {
+		data, err = loadPair(path, filepath.Join(filepath.Dir(path), expectedResultsFile))
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	test, err := ParseNISTTest(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	test.Source = path
+	return test, nil
+}
+
+// LoadNISTTests loads the vector set in a file, or every vector set under a
+// directory in path order. In each directory internalProjection.json is used
+// if present, else prompt.json with expectedResults.json; any other JSON
+// file with testGroups is loaded on its own.
+func LoadNISTTests(root string) ([]*NISTTest, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		test, err := LoadNISTTest(root)
+		if err != nil {
+			return nil, err
+		}
+		return []*NISTTest{test}, nil
+	}
+
+	var tests []*NISTTest
+	err = filepath.WalkDir(root, func(dir string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		found, err := loadDir(dir)
+		tests = append(tests, found...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("%s: %w", root, ErrNoVectorSets)
+	}
+	return tests, nil
+}
+
+// loadDir loads the vector sets directly inside dir
+func loadDir(dir string) ([]*NISTTest, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]bool)
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.EqualFold(filepath.Ext(e.Name()), ".json") {
+			files[e.Name()] = true
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var tests []*NISTTest
+	switch {
+	case files[internalProjectionFile]:
+		test, err := LoadNISTTest(filepath.Join(dir, internalProjectionFile))
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+	case files[promptFile]:
+		test, err := LoadNISTTest(filepath.Join(dir, promptFile))
+		if err != nil {
+			return nil, err
+		}
+		test.Source = dir
+		tests = append(tests, test)
+	}
+	for _, name := range names {
+		if name == internalProjectionFile || name == promptFile || name == expectedResultsFile {
+			continue
+		}
+		test, err := LoadNISTTest(filepath.Join(dir, name))
+		if errors.Is(err, ErrNoTestGroups) {
+			continue // e.g. a registration.json
+		}
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, test)
+	}
+	return tests, nil
+}
+
+// loadPair merges the answers in expectedResults into the test cases of
+// prompt, matching groups by tgId and cases by tcId
+func loadPair(promptPath, expectedPath string) ([]byte, error) {
+	var prompt, expected map[string]any
+	for path, dst := range map[string]*map[string]any{promptPath: &prompt, expectedPath: &expected} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := vectorSet(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber() // keep large numbers exact when writing them back
+		if err := decoder.Decode(dst); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	answers := make(map[string]map[string]any)
+	groupAnswers := make(map[string]map[string]any)
+	for _, g := range objects(expected["testGroups"]) {
+		groupAnswers[fmt.Sprint(g["tgId"])] = g
+		for _, t := range objects(g["tests"]) {
+			answers[fmt.Sprint(g["tgId"], "/", t["tcId"])] = t
+		}
+	}
+	for _, g := range objects(prompt["testGroups"]) {
+		for k, v := range groupAnswers[fmt.Sprint(g["tgId"])] {
+			if k != "tests" {
+				g[k] = v
+			}
+		}
+		for _, t := range objects(g["tests"]) {
+			answer, ok := answers[fmt.Sprint(g["tgId"], "/", t["tcId"])]
+			if !ok {
+				return nil, fmt.Errorf("%s has no answer for tgId %v tcId %v", expectedPath, g["tgId"], t["tcId"])
+			}
+			for k, v := range answer {
+				t[k] = v
+			}
+		}
+	}
+	return json.Marshal(prompt)
+}
+
+// vectorSet returns the vector set object in data, unwrapping the ACVP
+// exchange array
+func vectorSet(data []byte) (json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var parts []json.RawMessage
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON: %w", err)
+		}
+		for _, part := range parts {
+			if ok, _ := hasTestGroups(part); ok {
+				return part, nil
+			}
+		}
+		return nil, ErrNoTestGroups
+	}
+	ok, err := hasTestGroups(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if !ok {
+		return nil, ErrNoTestGroups
+	}
+	return data, nil
+}
+
+func hasTestGroups(data []byte) (bool, error) {
+	var probe struct {
+		TestGroups json.RawMessage `json:"testGroups"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return false, err
+	}
+	return probe.TestGroups != nil, nil
+}
+
+func objects(v any) []map[string]any {
+	list, _ := v.([]any)
+	var out []map[string]any
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
@@ -0,0 +1,265 @@
+package libs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown report format, choose text, json, junit or acvp")
+
+// Format is the rendering written by Report.Write
+type Format string
+
+const (
+	FormatText  Format = "text"  // one line per test case, then the summary
+	FormatJSON  Format = "json"  // the Report itself
+	FormatJUnit Format = "junit" // JUnit XML, one testsuite per vector set
+	FormatACVP  Format = "acvp"  // ACVP response: the computed values per tcId
+)
+
+// CaseResult is the outcome of one test case
+type CaseResult struct {
+	ID      int    `json:"tcId"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"` // the harness can't run it, see ErrNotSupported
+	Reason  string `json:"reason,omitempty"`  // why it failed or was skipped
+	// Duration is how long the check took
+	Duration time.Duration `json:"durationNs"`
+	// Output holds the values computed for the ACVP response, e.g. ct and tag
+	Output map[string]any `json:"output,omitempty"`
+}
+
+// GroupReport is the outcome of one test group
+type GroupReport struct {
+	ID        int          `json:"tgId"`
+	TestType  string       `json:"testType,omitempty"`
+	Direction string       `json:"direction,omitempty"`
+	Cases     []CaseResult `json:"cases"` // in tcId order
+	// Duration is the wall time from the first case starting to the last one
+	// finishing, so with several workers it is less than the sum of the cases
+	Duration time.Duration `json:"durationNs"`
+	Counts
+}
+
+func (g *GroupReport) addCase(c CaseResult) {
+	g.Cases = append(g.Cases, c)
+	switch {
+	case c.Passed:
+		g.Passed++
+	case c.Skipped:
+		g.Skipped++
+	default:
+		g.Failed++
+	}
+}
+
+// SetReport is the outcome of one vector set
+type SetReport struct {
+	VsID      int           `json:"vsId"`
+	Algorithm string        `json:"algorithm"`
+	Mode      string        `json:"algoMode,omitempty"`
+	Revision  string        `json:"revision,omitempty"`
+	Source    string        `json:"source"`
+	Groups    []GroupReport `json:"groups"`
+	Counts
+}
+
+// Name returns the algorithm and mode, as NISTTest.Name does
+func (s SetReport) Name() string {
+	return (&NISTTest{Algorithm: s.Algorithm, Mode: s.Mode}).Name()
+}
+
+// Report is the outcome of RunTest or RunAll
+type Report struct {
+	Sets        []SetReport `json:"sets"`
+	SkippedSets []string    `json:"skippedSets"`       // vector sets without a harness
+	Stopped     bool        `json:"stopped,omitempty"` // RunOptions.FailFast cut the run short
+	Counts
+}
+
+func (r *Report) merge(other Report) {
+	r.Sets = append(r.Sets, other.Sets...)
+	r.SkippedSets = append(r.SkippedSets, other.SkippedSets...)
+	r.Stopped = r.Stopped || other.Stopped
+	r.add(other.Counts)
+}
+
+// OK reports whether every test case passed
+func (r Report) OK() bool {
+	return r.Failed == 0
+}
+
+// ParseFormat returns the Format named s; empty means text
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(s); format {
+	case FormatText, FormatJSON, FormatJUnit, FormatACVP:
+		return format, nil
+	case "":
+		return FormatText, nil
+	}
+	return "", fmt.Errorf("%w (got %q)", ErrUnknownFormat, s)
+}
+
+// Write renders the report in the given format
+func (r Report) Write(w io.Writer, format Format) error {
+	format, err := ParseFormat(string(format))
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatJSON:
+		return r.WriteJSON(w)
+	case FormatJUnit:
+		return r.WriteJUnit(w)
+	case FormatACVP:
+		return r.WriteACVP(w)
+	}
+	return r.WriteText(w)
+}
+
+// WriteText prints every test case, then the counts per algorithm and group
+func (r Report) WriteText(w io.Writer) error {
+	for _, set := range r.Sets {
+		fmt.Fprintf(w, "Running tests for %s (%s)\n\n", set.Name(), set.Source)
+		for _, group := range set.Groups {
+			fmt.Fprintf(w, "Test Group %d (%s %s):\n", group.ID, group.TestType, group.Direction)
+			for _, c := range group.Cases {
+				switch {
+				case c.Passed:
+					fmt.Fprintf(w, "Test Case %d: ✓ Passed\n", c.ID)
+				case c.Skipped:
+					fmt.Fprintf(w, "Test Case %d: - Skipped: %s\n", c.ID, c.Reason)
+				default:
+					fmt.Fprintf(w, "Test Case %d: ❌ Failed: %s\n", c.ID, c.Reason)
+				}
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return r.Summary().Print(w)
+}
+
+// WriteJSON writes the report as indented JSON
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     string      `xml:"time,attr,omitempty"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr,omitempty"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes JUnit XML: a testsuite per vector set and a testcase per
+// tcId, named by group and direction, with the time each took. Skipped cases
+// count in tests, as JUnit expects, and skipped sets appear as skipped suites.
+func (r Report) WriteJUnit(w io.Writer) error {
+	suites := junitSuites{Tests: r.Passed + r.Failed + r.Skipped, Failures: r.Failed, Skipped: r.Skipped}
+	for _, set := range r.Sets {
+		suite := junitSuite{Name: fmt.Sprintf("%s vsId %d (%s)", set.Name(), set.VsID, set.Source), Tests: set.Passed + set.Failed + set.Skipped, Failures: set.Failed, Skipped: set.Skipped}
+		var elapsed time.Duration
+		for _, group := range set.Groups {
+			elapsed += group.Duration
+			for _, c := range group.Cases {
+				tc := junitCase{
+					ClassName: fmt.Sprintf("%s.tg%d.%s", set.Name(), group.ID, group.Direction),
+					Name:      fmt.Sprintf("tc%d", c.ID),
+					Time:      junitTime(c.Duration),
+				}
+				switch {
+				case c.Skipped:
+					tc.Skipped = &junitMessage{Message: c.Reason}
+				case !c.Passed:
+					tc.Failure = &junitMessage{Message: c.Reason}
+				}
+				suite.Cases = append(suite.Cases, tc)
+			}
+		}
+		suite.Time = junitTime(elapsed)
+		suites.Suites = append(suites.Suites, suite)
+	}
+	for _, skipped := range r.SkippedSets {
+		suites.Suites = append(suites.Suites, junitSuite{
+			Name:    skipped,
+			Skipped: 1,
+			Cases:   []junitCase{{ClassName: skipped, Name: "vector set", Skipped: &junitMessage{Message: "no harness for this algorithm"}}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitTime formats d in seconds, as the time attributes of JUnit take it
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.6f", d.Seconds())
+}
+
+// WriteACVP writes the ACVP response: the exchange array with acvVersion
+// first, then one element per vector set holding the values computed for
+// each tcId. A case that could not be computed or was skipped has only its tcId.
+func (r Report) WriteACVP(w io.Writer) error {
+	type response struct {
+		VsID      int    `json:"vsId"`
+		Algorithm string `json:"algorithm"`
+		Mode      string `json:"mode,omitempty"`
+		Revision  string `json:"revision,omitempty"`
+		Groups    []any  `json:"testGroups"`
+	}
+	exchange := []any{map[string]string{"acvVersion": "1.0"}}
+	for _, set := range r.Sets {
+		resp := response{VsID: set.VsID, Algorithm: set.Algorithm, Mode: set.Mode, Revision: set.Revision, Groups: []any{}}
+		for _, group := range set.Groups {
+			tests := make([]map[string]any, 0, len(group.Cases))
+			for _, c := range group.Cases {
+				test := map[string]any{"tcId": c.ID}
+				for k, v := range c.Output {
+					test[k] = v
+				}
+				tests = append(tests, test)
+			}
+			resp.Groups = append(resp.Groups, map[string]any{"tgId": group.ID, "tests": tests})
+		}
+		exchange = append(exchange, resp)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(exchange)
+}
@@ -0,0 +1,168 @@
+package libs
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counts tallies test case outcomes
+type Counts struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"` // cases the harness can't run
+}
+
+func (c *Counts) add(other Counts) {
+	c.Passed += other.Passed
+	c.Failed += other.Failed
+	c.Skipped += other.Skipped
+}
+
+// GroupSummary is the outcome of one test group
+type GroupSummary struct {
+	Source    string        `json:"source"`
+	ID        int           `json:"tgId"`
+	Direction string        `json:"direction,omitempty"`
+	Duration  time.Duration `json:"durationNs"`
+	Counts
+}
+
+// AlgorithmSummary adds up the groups of every vector set of one algorithm
+type AlgorithmSummary struct {
+	Algorithm string         `json:"algorithm"`
+	Sets      int            `json:"sets"`
+	Groups    []GroupSummary `json:"groups"`
+	Counts
+}
+
+// Summary is the counts of a Report per algorithm and group
+type Summary struct {
+	Algorithms  []AlgorithmSummary `json:"algorithms"`  // sorted by name
+	SkippedSets []string           `json:"skippedSets"` // vector sets RunTest has no harness for
+	Stopped     bool               `json:"stopped,omitempty"`
+	Counts
+}
+
+// RunOptions says how RunTestWith and RunAllWith execute the test cases
+type RunOptions struct {
+	// Workers is how many cases are checked at once; 0 or 1 checks them one
+	// after another. The report lists cases in tcId order either way.
+	Workers int
+	// FailFast stops at the first failed case. Cases already running finish,
+	// the rest are left out of the report and Report.Stopped is set.
+	FailFast bool
+}
+
+// RunAll runs every vector set in tests and reports them together
+func RunAll(tests []*NISTTest) Report {
+	return RunAllWith(tests, RunOptions{})
+}
+
+// RunAllWith is RunAll with options; with FailFast no further vector set
+// is run after a failure
+func RunAllWith(tests []*NISTTest, opts RunOptions) Report {
+	report := Report{SkippedSets: []string{}}
+	var stop atomic.Bool
+	for _, test := range tests {
+		if stop.Load() {
+			break
+		}
+		report.merge(runTest(test, opts, &stop))
+	}
+	return report
+}
+
+// job is a test case queued for the worker pool, with its outcome
+type job struct {
+	Case
+	group      int // index in the vector set
+	result     CaseResult
+	start, end time.Time
+	ran        bool
+}
+
+// runJobs checks every job on up to opts.Workers goroutines. Each worker
+// writes only the job it took, so the results need no further locking.
+func runJobs(jobs []job, opts RunOptions, stop *atomic.Bool) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(opts.Workers, 1), len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if stop.Load() {
+					continue // drain what was queued before the stop
+				}
+				j := &jobs[i]
+				j.start = time.Now()
+				output, err := j.Check()
+				j.end = time.Now()
+				j.result = caseResult(j.ID, output, err)
+				j.result.Duration = j.end.Sub(j.start)
+				j.ran = true
+				if opts.FailFast && !j.result.Passed && !j.result.Skipped {
+					stop.Store(true)
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		if stop.Load() {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// Summary adds up the report per algorithm and group
+func (r Report) Summary() Summary {
+	summary := Summary{SkippedSets: r.SkippedSets, Stopped: r.Stopped, Counts: r.Counts}
+	byName := make(map[string]*AlgorithmSummary)
+	for _, set := range r.Sets {
+		algo := byName[set.Name()]
+		if algo == nil {
+			algo = &AlgorithmSummary{Algorithm: set.Name()}
+			byName[set.Name()] = algo
+		}
+		algo.Sets++
+		algo.add(set.Counts)
+		for _, group := range set.Groups {
+			algo.Groups = append(algo.Groups, GroupSummary{Source: set.Source, ID: group.ID, Direction: group.Direction, Duration: group.Duration, Counts: group.Counts})
+		}
+	}
+
+	for _, algo := range byName {
+		summary.Algorithms = append(summary.Algorithms, *algo)
+	}
+	sort.Slice(summary.Algorithms, func(i, j int) bool {
+		return summary.Algorithms[i].Algorithm < summary.Algorithms[j].Algorithm
+	})
+	return summary
+}
+
+// Print writes the counts as a table, one line per algorithm and group with
+// the time its cases took
+func (s Summary) Print(w io.Writer) error {
+	fmt.Fprintln(w, "Summary:")
+	for _, algo := range s.Algorithms {
+		fmt.Fprintf(w, "%-24s %5d passed %5d failed %5d skipped (%d vector set(s))\n", algo.Algorithm, algo.Passed, algo.Failed, algo.Skipped, algo.Sets)
+		for _, group := range algo.Groups {
+			fmt.Fprintf(w, "  group %-4d %-8s %5d passed %5d failed %5d skipped %10s  %s\n", group.ID, group.Direction, group.Passed, group.Failed, group.Skipped, group.Duration.Round(time.Microsecond), group.Source)
+		}
+	}
+	for _, skipped := range s.SkippedSets {
+		fmt.Fprintf(w, "Skipped %s: no harness for this algorithm\n", skipped)
+	}
+	if s.Stopped {
+		fmt.Fprintln(w, "Stopped at the first failure (fail-fast)")
+	}
+	_, err := fmt.Fprintf(w, "Total: %d passed, %d failed, %d skipped\n", s.Passed, s.Failed, s.Skipped)
+	return err
+}
@@ -0,0 +1,144 @@
+package libs
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// hashes maps the ACVP algorithm names of SHA-2 and SHA-3 to their
+// constructors; the HMAC and HKDF harnesses look hashes up here as well
+var hashes = map[string]func() hash.Hash{
+	"SHA2-224":     sha256.New224,
+	"SHA2-256":     sha256.New,
+	"SHA2-384":     sha512.New384,
+	"SHA2-512":     sha512.New,
+	"SHA2-512/224": sha512.New512_224,
+	"SHA2-512/256": sha512.New512_256,
+	"SHA3-224":     func() hash.Hash { return sha3.New224() },
+	"SHA3-256":     func() hash.Hash { return sha3.New256() },
+	"SHA3-384":     func() hash.Hash { return sha3.New384() },
+	"SHA3-512":     func() hash.Hash { return sha3.New512() },
+}
+
+func init() {
+	for name, newHash := range hashes {
+		Register(CaseHarness(checkSHACase(name, newHash)), name)
+	}
+}
+
+// SHAGroup shares its parameters between SHA-2 or SHA-3 test cases
+type SHAGroup struct {
+	TestType   string `json:"testType"`   // AFT or MCT, LDT is skipped
+	MCTVersion string `json:"mctVersion"` // only standard Monte Carlo tests are run
+}
+
+// SHACase is one SHA-2 or SHA-3 test vector. For an MCT, Msg is the seed and
+// ResultsArray holds the digest after each of the 100 outer iterations.
+type SHACase struct {
+	Msg          string      `json:"msg"`
+	Len          int         `json:"len"` // message length in bits
+	MD           string      `json:"md"`
+	ResultsArray []SHAResult `json:"resultsArray"`
+}
+
+// SHAResult is one outer iteration of a Monte Carlo test
+type SHAResult struct {
+	MD string `json:"md"`
+}
+
+// checkSHACase returns the check for the hash called name
+func checkSHACase(name string, newHash func() hash.Hash) func(group SHAGroup, t SHACase) (map[string]any, error) {
+	return func(group SHAGroup, t SHACase) (map[string]any, error) {
+		if t.Len%8 != 0 {
+			return nil, fmt.Errorf("%w: message of %d bits is not whole bytes", ErrNotSupported, t.Len)
+		}
+		msg, err := hex.DecodeString(t.Msg)
+		if err != nil {
+			return nil, fmt.Errorf("decoding msg: %w", err)
+		}
+
+		switch group.TestType {
+		case "AFT":
+			expected, err := hex.DecodeString(t.MD)
+			if err != nil {
+				return nil, fmt.Errorf("decoding md: %w", err)
+			}
+			h := newHash()
+			h.Write(msg)
+			md := h.Sum(nil)
+			output := map[string]any{"md": hex.EncodeToString(md)}
+			if !compareBytes(md, expected) {
+				return output, fmt.Errorf("digest mismatch: expected %X, got %X", expected, md)
+			}
+			return output, nil
+		case "MCT":
+			if group.MCTVersion != "" && group.MCTVersion != "standard" {
+				return nil, fmt.Errorf("%w: %s Monte Carlo tests", ErrNotSupported, group.MCTVersion)
+			}
+			return checkSHAMonteCarlo(strings.HasPrefix(name, "SHA3-"), newHash, msg, t.ResultsArray)
+		}
+		return nil, fmt.Errorf("%w: %s tests", ErrNotSupported, group.TestType)
+	}
+}
+
+// checkSHAMonteCarlo runs the Monte Carlo test from seed and compares each of
+// the 100 outer iterations with expected
+func checkSHAMonteCarlo(isSHA3 bool, newHash func() hash.Hash, seed []byte, expected []SHAResult) (map[string]any, error) {
+	mds := shaMonteCarlo(isSHA3, newHash, seed)
+	results := make([]map[string]any, len(mds))
+	for i, md := range mds {
+		results[i] = map[string]any{"md": hex.EncodeToString(md)}
+	}
+	output := map[string]any{"resultsArray": results}
+
+	if len(expected) != len(mds) {
+		return output, fmt.Errorf("expected %d results, got %d", len(expected), len(mds))
+	}
+	for i, md := range mds {
+		want, err := hex.DecodeString(expected[i].MD)
+		if err != nil {
+			return output, fmt.Errorf("decoding md of result %d: %w", i, err)
+		}
+		if !compareBytes(md, want) {
+			return output, fmt.Errorf("digest mismatch at iteration %d: expected %X, got %X", i, want, md)
+		}
+	}
+	return output, nil
+}
+
+// shaMonteCarlo returns the digests of the 100 outer iterations of the ACVP
+// Monte Carlo test. SHA-2 hashes the last three digests together 1000 times
+// per iteration, SHA-3 hashes the last digest 1000 times.
+func shaMonteCarlo(isSHA3 bool, newHash func() hash.Hash, seed []byte) [][]byte {
+	h := newHash()
+	sum := func(parts ...[]byte) []byte {
+		h.Reset()
+		for _, p := range parts {
+			h.Write(p)
+		}
+		return h.Sum(nil)
+	}
+
+	results := make([][]byte, 0, 100)
+	md := seed
+	for range 100 {
+		if isSHA3 {
+			for range 1000 {
+				md = sum(md)
+			}
+		} else {
+			a, b, c := md, md, md
+			for range 1000 {
+				a, b, c = b, c, sum(a, b, c)
+			}
+			md = c
+		}
+		results = append(results, md)
+	}
+	return results
+}
@@ -1,19 +1,95 @@
 package main
 
 import (
+    "flag"
+    "fmt"
     "log"
+    "os"
+    "runtime"
     "test-nist/libs"
 )
 
+const defaultURL = "https://raw.githubusercontent.com/usnistgov/ACVP-Server/master/gen-val/json-files/ACVP-AES-GCM-1.0/internalProjection.json"
+
+// Exit codes: 1 when a test case failed, 2 when the vectors or the report could not be handled
+const (
+    exitFailed = 1
+    exitError  = 2
+)
+
 func main() {
-    url := "https://raw.githubusercontent.com/usnistgov/ACVP-Server/master/gen-val/json-files/ACVP-AES-GCM-1.0/internalProjection.json"
-    
-    test, err := libs.FetchNISTTest(url)
+    vectors := flag.String("vectors", "", "ACVP vector file or directory to run offline (skips the download)")
+    url := flag.String("url", defaultURL, "URL of an ACVP vector set to download")
+    format := flag.String("format", string(libs.FormatText), "report format: text, json, junit or acvp")
+    out := flag.String("out", "-", "file to write the report to, - for stdout")
+    workers := flag.Int("workers", 1, "test cases to check at once, 0 for one per CPU")
+    failFast := flag.Bool("fail-fast", false, "stop at the first failed test case")
+    algorithms := flag.Bool("algorithms", false, "list the algorithms that have a harness and exit")
+    flag.Parse()
+    if *algorithms {
+        for _, name := range libs.Algorithms() {
+            fmt.Println(name)
+        }
+        return
+    }
+    reportFormat, err := libs.ParseFormat(*format)
+    if err != nil {
+        fatal("%v", err)
+    }
+
+    var tests []*libs.NISTTest
+    if *vectors != "" {
+        loaded, err := libs.LoadNISTTests(*vectors)
+        if err != nil {
+            fatal("Error loading NIST test vectors: %v", err)
+        }
+        tests = loaded
+    } else {
+        test, err := libs.FetchNISTTest(*url)
+        if err != nil {
+            fatal("Error fetching NIST test vectors: %v", err)
+        }
+        tests = []*libs.NISTTest{test}
+    }
+
+    if *workers <= 0 {
+        *workers = runtime.NumCPU()
+    }
+    report := libs.RunAllWith(tests, libs.RunOptions{Workers: *workers, FailFast: *failFast})
+
+    w := os.Stdout
+    if *out != "-" {
+        file, err := os.Create(*out)
+        if err != nil {
+            fatal("Error creating report: %v", err)
+        }
+        w = file
+    }
+    err = report.Write(w, reportFormat)
+    if w != os.Stdout {
+        // os.Exit skips deferred calls, so close (and catch write errors) here.
+        if closeErr := w.Close(); err == nil {
+            err = closeErr
+        }
+    }
     if err != nil {
-        log.Fatalf("Error fetching NIST test vectors: %v", err)
+        fatal("Error writing report: %v", err)
+    }
+    if reportFormat != libs.FormatText || *out != "-" {
+        // Keep the outcome visible in the CI log when the report goes elsewhere.
+        fmt.Fprintf(os.Stderr, "Total: %d passed, %d failed, %d skipped, %d vector set(s) skipped\n", report.Passed, report.Failed, report.Skipped, len(report.SkippedSets))
+        if report.Stopped {
+            fmt.Fprintln(os.Stderr, "Stopped at the first failure (-fail-fast)")
+        }
     }
+    if !report.OK() {
+        os.Exit(exitFailed)
+    }
+}
 
-    libs.RunTest(test)
+func fatal(format string, args ...any) {
+    log.Printf(format, args...)
+    os.Exit(exitError)
 }
 
 
@@ -21,20 +97,35 @@ func main() {
 /*
 % go mod init nist-test
 % go run main.go
+% go run main.go -vectors ./json-files          # offline: a file, or a tree of ACVP vector sets
+% go run main.go -vectors ./json-files -format junit -out report.xml   # also json or acvp; exits 1 on any failure
+% go run main.go -vectors ./json-files -workers 0 -fail-fast   # check cases on every CPU, stop at the first failure
+% go run main.go -algorithms                    # AES-GCM/CBC/CTR/ECB, SHA2-*, SHA3-*, HMAC-*, KDA-HKDF
 
 Error Handling: The errors are logged with details to identify failures.
 Comparison: Ciphertext and tag are compared with the expected values.
 Logging: Detailed logs ensure traceability of test results.
 
 
-Running tests for Algorithm: AES
-Mode: GCM
+% go run main.go -vectors ./json-files/gcm
+Running tests for ACVP-AES-GCM (json-files/gcm/internalProjection.json)
+
+Test Group 1 (AFT decrypt):
+Test Case 1: ✓ Passed
+Test Case 2: ✓ Passed
+Test Case 3: ✓ Passed
+
+Test Group 2 (AFT encrypt):
+Test Case 4: ✓ Passed
+...
 
-Test Case 1:
-Test case 1 passed!
-Decrypted Plaintext: Hello, NIST!
+Summary:
+ACVP-AES-GCM                10 passed     0 failed     0 skipped (1 vector set(s))
+  group 1    decrypt      3 passed     0 failed     0 skipped      203µs  json-files/gcm/internalProjection.json
+  group 2    encrypt      1 passed     0 failed     0 skipped        6µs  json-files/gcm/internalProjection.json
+  ...
+Total: 10 passed, 0 failed, 0 skipped
 
-Test Case 2:
-Test case 2 failed: Mismatch in ciphertext or tag
+A failing case reads "Test Case 2: ❌ Failed: <reason>", a skipped one "Test Case 3: - Skipped: <reason>".
 
 */
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"test-nist/libs"
+)
+
+const gcmDir = "testdata/gcm"
+
+// TestGCMNegativeVectors passes expected-failure cases only when the tag is
+// rejected, including truncated tags and a 64-bit IV
+func TestGCMNegativeVectors(t *testing.T) {
+	tests, err := libs.LoadNISTTests(gcmDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := libs.RunAll(tests)
+	if !report.OK() || report.Passed != 10 {
+		t.Fatalf("got %+v", report.Counts)
+	}
+	if rejected := report.Sets[0].Groups[0].Cases[1]; rejected.Output["testPassed"] != false {
+		t.Errorf("rejected case output: %+v", rejected.Output)
+	}
+
+	// Swap the expectations: now the valid tag must fail and the forged one decrypt.
+	var group map[string]any
+	if err := json.Unmarshal(tests[0].Groups[0], &group); err != nil {
+		t.Fatal(err)
+	}
+	swapped := group["tests"].([]any)
+	swapped[0].(map[string]any)["testPassed"] = false
+	delete(swapped[1].(map[string]any), "testPassed")
+	if tests[0].Groups[0], err = json.Marshal(group); err != nil {
+		t.Fatal(err)
+	}
+	cases := libs.RunTest(tests[0]).Sets[0].Groups[0].Cases
+	if cases[0].Passed || !strings.Contains(cases[0].Reason, "tag accepted") {
+		t.Errorf("valid tag expected to fail: %+v", cases[0])
+	}
+	if cases[1].Passed || !strings.Contains(cases[1].Reason, "tag rejected") {
+		t.Errorf("forged tag expected to decrypt: %+v", cases[1])
+	}
+}
+
+// TestGCMTagLengths checks truncation and the tag sizes SP 800-38D allows
+func TestGCMTagLengths(t *testing.T) {
+	key, _ := hex.DecodeString("feffe9928665731c6d6a8f9467308308")
+	iv, _ := hex.DecodeString("cafebabefacedbad")
+	pt := []byte("Hello, NIST!")
+
+	ct, full, err := libs.EncryptAESGCMWithParams(key, iv, pt, nil, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tagLen := range []int{4, 8, 12, 16} {
+		ct2, tag, err := libs.EncryptAESGCMWithParams(key, iv, pt, nil, tagLen)
+		if err != nil || hex.EncodeToString(tag) != hex.EncodeToString(full[:tagLen]) || string(ct2) != string(ct) {
+			t.Errorf("encrypt with %d-byte tag: %x, %v", tagLen, tag, err)
+		}
+		got, err := libs.DecryptAESGCMWithParams(key, iv, ct, tag, nil, tagLen)
+		if err != nil || string(got) != string(pt) {
+			t.Errorf("decrypt with %d-byte tag: %q, %v", tagLen, got, err)
+		}
+		forged := append([]byte(nil), tag...)
+		forged[0] ^= 1
+		if _, err := libs.DecryptAESGCMWithParams(key, iv, ct, forged, nil, tagLen); !errors.Is(err, libs.ErrAuthentication) {
+			t.Errorf("forged %d-byte tag: got %v, want ErrAuthentication", tagLen, err)
+		}
+	}
+
+	for _, tagLen := range []int{0, 7, 17} {
+		if _, _, err := libs.EncryptAESGCMWithParams(key, iv, pt, nil, tagLen); !errors.Is(err, libs.ErrInvalidTagLength) {
+			t.Errorf("encrypt with %d-byte tag: got %v, want ErrInvalidTagLength", tagLen, err)
+		}
+	}
+	if _, err := libs.DecryptAESGCMWithParams(key, iv, ct, full[:12], nil, 16); !errors.Is(err, libs.ErrInvalidTagLength) {
+		t.Errorf("short tag: got %v, want ErrInvalidTagLength", err)
+	}
+}
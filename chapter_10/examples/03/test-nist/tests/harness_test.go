@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	"test-nist/libs"
+)
+
+const harnessesDir = "testdata/harnesses"
+
+// TestHarnesses runs a vector set per algorithm: AES-ECB, CBC and CTR, SHA-2
+// and SHA-3 with Monte Carlo tests, HMAC and HKDF
+func TestHarnesses(t *testing.T) {
+	tests, err := libs.LoadNISTTests(harnessesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := libs.RunAll(tests)
+	if len(report.SkippedSets) != 0 {
+		t.Errorf("sets without a harness: %v", report.SkippedSets)
+	}
+	// Skipped: the AES MCT, a 100-bit CTR payload and a 1-bit message per SHA.
+	if !report.OK() || report.Passed != 18 || report.Skipped != 4 {
+		for _, set := range report.Sets {
+			for _, group := range set.Groups {
+				for _, c := range group.Cases {
+					if !c.Passed {
+						t.Logf("%s tg%d tc%d: %s", set.Name(), group.ID, c.ID, c.Reason)
+					}
+				}
+			}
+		}
+		t.Fatalf("got %+v, want 18 passed and 4 skipped", report.Counts)
+	}
+
+	names := make([]string, len(report.Sets))
+	for i, set := range report.Sets {
+		names[i] = set.Name()
+	}
+	want := []string{"ACVP-AES-CBC", "ACVP-AES-CTR", "ACVP-AES-ECB", "HMAC-SHA2-256", "KDA-HKDF", "SHA2-256", "SHA3-256"}
+	if !slices.Equal(names, want) {
+		t.Errorf("sets: got %v, want %v", names, want)
+	}
+	for _, name := range want {
+		if !slices.Contains(libs.Algorithms(), name) {
+			t.Errorf("%s is not registered", name)
+		}
+	}
+}
+
+// TestHarnessMismatch reports the computed value and the reason for a wrong answer
+func TestHarnessMismatch(t *testing.T) {
+	test, err := libs.ParseNISTTest([]byte(`{"algorithm": "HMAC-SHA2-256", "testGroups": [{"tgId": 1, "testType": "AFT", "macLen": 128,
+		"tests": [{"tcId": 1, "key": "4a656665", "msg": "00", "mac": "00000000000000000000000000000000"}, {"tcId": 2, "key": "zz"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := libs.RunTest(test).Sets[0].Groups[0].Cases
+	if c := cases[0]; c.Passed || !strings.Contains(c.Reason, "MAC mismatch") || len(c.Output["mac"].(string)) != 32 {
+		t.Errorf("wrong MAC: %+v", c)
+	}
+	if c := cases[1]; c.Passed || c.ID != 2 || !strings.Contains(c.Reason, "decoding key") {
+		t.Errorf("bad hex: %+v", c)
+	}
+}
+
+// TestRegister plugs in a harness for a new algorithm
+func TestRegister(t *testing.T) {
+	test, err := libs.ParseNISTTest([]byte(`{"algorithm": "TEST", "mode": "ECHO", "testGroups": [{"tgId": 7, "tests": [{"tcId": 1, "in": "a", "out": "a"}, {"tcId": 2, "in": "b", "out": "c"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type echoCase struct {
+		In  string `json:"in"`
+		Out string `json:"out"`
+	}
+	libs.Register(libs.CaseHarness(func(group json.RawMessage, c echoCase) (map[string]any, error) {
+		if c.In != c.Out {
+			return nil, libs.ErrNotSupported
+		}
+		return map[string]any{"out": c.In}, nil
+	}), "TEST-ECHO")
+
+	report := libs.RunTest(test)
+	if report.Passed != 1 || report.Skipped != 1 || report.Sets[0].Groups[0].ID != 7 {
+		t.Errorf("got %+v", report)
+	}
+}
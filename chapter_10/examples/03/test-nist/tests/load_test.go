@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"test-nist/libs"
+)
+
+const vectorsDir = "testdata/vectors"
+
+// TestLoadPromptAndExpectedResults merges the answers into the prompt by tgId and tcId
+func TestLoadPromptAndExpectedResults(t *testing.T) {
+	test, err := libs.LoadNISTTest(filepath.Join(vectorsDir, "ACVP-AES-GCM-1.0", "prompt.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if test.Name() != "ACVP-AES-GCM" || len(test.Groups) != 2 {
+		t.Fatalf("got %s with %d groups", test.Name(), len(test.Groups))
+	}
+	encrypt := gcmCases(t, test.Groups[0])[1]
+	if encrypt.PT != "00000000000000000000000000000000" || encrypt.CT != "0388dace60b6a392f328c2b971b2fe78" {
+		t.Errorf("encrypt case: %+v", encrypt)
+	}
+	if decrypt := gcmCases(t, test.Groups[1])[0]; decrypt.PT != "48656c6c6f2c204e49535421" || decrypt.Tag == "" {
+		t.Errorf("decrypt case: %+v", decrypt)
+	}
+}
+
+// gcmCases decodes the test cases of an AES-GCM group
+func gcmCases(t *testing.T, group json.RawMessage) []libs.GCMCase {
+	t.Helper()
+	var decoded struct {
+		Tests []libs.GCMCase `json:"tests"`
+	}
+	if err := json.Unmarshal(group, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded.Tests
+}
+
+// TestLoadMissingAnswer rejects a prompt case that expectedResults does not answer
+func TestLoadMissingAnswer(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "prompt.json"), []byte(`{"algorithm":"ACVP-AES-GCM","testGroups":[{"tgId":1,"tests":[{"tcId":1},{"tcId":2}]}]}`), 0o644)
+	os.WriteFile(filepath.Join(dir, "expectedResults.json"), []byte(`{"testGroups":[{"tgId":1,"tests":[{"tcId":1,"ct":""}]}]}`), 0o644)
+	if _, err := libs.LoadNISTTests(dir); err == nil {
+		t.Error("loaded a prompt with an unanswered case")
+	}
+	if _, err := libs.LoadNISTTests(t.TempDir()); !errors.Is(err, libs.ErrNoVectorSets) {
+		t.Errorf("empty directory: got %v, want ErrNoVectorSets", err)
+	}
+}
+
+// TestLoadDirectory finds the prompt pair, the exchange form and projections,
+// and skips JSON files that are not vector sets
+func TestLoadDirectory(t *testing.T) {
+	tests, err := libs.LoadNISTTests(vectorsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []string
+	for _, test := range tests {
+		sources = append(sources, test.Source)
+	}
+	want := []string{
+		filepath.Join(vectorsDir, "ACVP-AES-GCM-1.0"),
+		filepath.Join(vectorsDir, "ACVP-TDES-ECB-1.0", "internalProjection.json"),
+		filepath.Join(vectorsDir, "exchange", "gcm.json"),
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("got %v, want %v", sources, want)
+	}
+	for i := range want {
+		if sources[i] != want[i] {
+			t.Errorf("set %d: got %s, want %s", i, sources[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"test-nist/libs"
+)
+
+// TestWorkersKeepOrder checks that a worker pool gives the same report as a
+// sequential run, in tcId order, with timings
+func TestWorkersKeepOrder(t *testing.T) {
+	var tests []*libs.NISTTest
+	for _, dir := range []string{vectorsDir, gcmDir, harnessesDir} {
+		loaded, err := libs.LoadNISTTests(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, loaded...)
+	}
+
+	var want bytes.Buffer
+	sequential := libs.RunAll(tests)
+	sequential.WriteACVP(&want)
+	for _, workers := range []int{2, 8} {
+		report := libs.RunAllWith(tests, libs.RunOptions{Workers: workers})
+		var got bytes.Buffer
+		report.WriteACVP(&got)
+		if report.Counts != sequential.Counts || got.String() != want.String() {
+			t.Errorf("%d workers: got %+v, want %+v", workers, report.Counts, sequential.Counts)
+		}
+
+		for _, set := range report.Sets {
+			for _, group := range set.Groups {
+				// A coarse clock can measure 0, so only negative durations are wrong.
+				if group.Duration < 0 {
+					t.Errorf("%s tg%d took %v", set.Name(), group.ID, group.Duration)
+				}
+				for i, c := range group.Cases {
+					if i > 0 && c.ID < group.Cases[i-1].ID {
+						t.Errorf("%s tg%d: tc%d after tc%d", set.Name(), group.ID, c.ID, group.Cases[i-1].ID)
+					}
+					if c.Duration > group.Duration {
+						t.Errorf("%s tc%d took %v, its group %v", set.Name(), c.ID, c.Duration, group.Duration)
+					}
+				}
+			}
+		}
+	}
+}
+
+// hmacSet returns an HMAC-SHA2-256 vector set of n cases where only the
+// case numbered bad has a wrong MAC
+func hmacSet(t *testing.T, n, bad int) *libs.NISTTest {
+	t.Helper()
+	var cases []string
+	for i := 1; i <= n; i++ {
+		mac := "5bdcc146bf60754e6a042426089575c7"
+		if i == bad {
+			mac = strings.Repeat("0", 32)
+		}
+		cases = append(cases, fmt.Sprintf(`{"tcId": %d, "key": "4a656665", "msg": "7768617420646f2079612077616e7420666f72206e6f7468696e673f", "mac": %q}`, i, mac))
+	}
+	test, err := libs.ParseNISTTest([]byte(`{"algorithm": "HMAC-SHA2-256", "testGroups": [{"tgId": 1, "testType": "AFT", "macLen": 128, "tests": [` + strings.Join(cases, ",") + `]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return test
+}
+
+// TestFailFast stops at the first mismatch, also across vector sets
+func TestFailFast(t *testing.T) {
+	report := libs.RunTestWith(hmacSet(t, 50, 3), libs.RunOptions{FailFast: true})
+	if !report.Stopped || report.Passed != 2 || report.Failed != 1 || len(report.Sets[0].Groups[0].Cases) != 3 {
+		t.Errorf("sequential: got %+v, stopped %t", report.Counts, report.Stopped)
+	}
+
+	report = libs.RunTestWith(hmacSet(t, 500, 1), libs.RunOptions{Workers: 4, FailFast: true})
+	if !report.Stopped || report.Failed != 1 || report.Passed == 499 {
+		t.Errorf("4 workers: got %+v, stopped %t", report.Counts, report.Stopped)
+	}
+	cases := report.Sets[0].Groups[0].Cases
+	for i := 1; i < len(cases); i++ {
+		if cases[i].ID <= cases[i-1].ID {
+			t.Fatalf("cases out of order: tc%d after tc%d", cases[i].ID, cases[i-1].ID)
+		}
+	}
+
+	all := libs.RunAllWith([]*libs.NISTTest{hmacSet(t, 5, 5), hmacSet(t, 5, 0)}, libs.RunOptions{FailFast: true})
+	if len(all.Sets) != 1 || !all.Stopped {
+		t.Errorf("ran %d sets after a failure, want 1", len(all.Sets))
+	}
+	if all := libs.RunAll([]*libs.NISTTest{hmacSet(t, 5, 5), hmacSet(t, 5, 0)}); all.Stopped || all.Passed != 9 {
+		t.Errorf("without fail-fast: got %+v, stopped %t", all.Counts, all.Stopped)
+	}
+}
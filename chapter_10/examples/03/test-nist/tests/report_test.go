@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"test-nist/libs"
+)
+
+func runVectors(t *testing.T) libs.Report {
+	t.Helper()
+	tests, err := libs.LoadNISTTests(vectorsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return libs.RunAll(tests)
+}
+
+// TestRunTestReport records every case with its group, direction and reason
+func TestRunTestReport(t *testing.T) {
+	test, err := libs.LoadNISTTest(filepath.Join(vectorsDir, "exchange", "gcm.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := libs.RunTest(test)
+	if len(report.Sets) != 1 || len(report.Sets[0].Groups) != 1 {
+		t.Fatalf("got %+v", report)
+	}
+	group := report.Sets[0].Groups[0]
+	if group.ID != 1 || group.Direction != "encrypt" || len(group.Cases) != 2 {
+		t.Fatalf("group: %+v", group)
+	}
+	if c := group.Cases[0]; c.ID != 1 || !c.Passed || c.Reason != "" {
+		t.Errorf("case 1: %+v", c)
+	}
+	if c := group.Cases[1]; c.ID != 2 || c.Passed || !strings.Contains(c.Reason, "tag mismatch") {
+		t.Errorf("case 2: %+v", c)
+	}
+}
+
+// TestReportJSONAndJUnit checks that both renderings parse and carry the counts
+func TestReportJSONAndJUnit(t *testing.T) {
+	report := runVectors(t)
+
+	var out bytes.Buffer
+	if err := report.Write(&out, libs.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded libs.Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Passed != 4 || decoded.Failed != 1 || len(decoded.Sets) != 2 || len(decoded.SkippedSets) != 1 {
+		t.Errorf("JSON: got %+v", decoded.Counts)
+	}
+
+	out.Reset()
+	if err := report.Write(&out, libs.FormatJUnit); err != nil {
+		t.Fatal(err)
+	}
+	var junit struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Cases []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(out.Bytes(), &junit); err != nil {
+		t.Fatal(err)
+	}
+	if junit.Tests != 5 || junit.Failures != 1 || len(junit.Suites) != 3 {
+		t.Fatalf("JUnit: got %d tests, %d failures, %d suites", junit.Tests, junit.Failures, len(junit.Suites))
+	}
+	if failed := junit.Suites[1].Cases[1]; failed.Name != "tc2" || failed.Failure == nil {
+		t.Errorf("JUnit failure: got %+v", failed)
+	}
+
+	if err := report.Write(&out, "yaml"); !errors.Is(err, libs.ErrUnknownFormat) {
+		t.Errorf("unknown format: got %v", err)
+	}
+}
+
+// TestReportACVP checks that the response for a passing set carries the
+// same answers as expectedResults.json
+func TestReportACVP(t *testing.T) {
+	test, err := libs.LoadNISTTest(filepath.Join(vectorsDir, "ACVP-AES-GCM-1.0", "prompt.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := libs.RunTest(test).Write(&out, libs.FormatACVP); err != nil {
+		t.Fatal(err)
+	}
+
+	type vectorSet struct {
+		VsID   int `json:"vsId"`
+		Groups []struct {
+			ID    int              `json:"tgId"`
+			Tests []map[string]any `json:"tests"`
+		} `json:"testGroups"`
+	}
+	var exchange []json.RawMessage
+	if err := json.Unmarshal(out.Bytes(), &exchange); err != nil || len(exchange) != 2 {
+		t.Fatalf("got %s, %v", out.String(), err)
+	}
+	var got, want vectorSet
+	json.Unmarshal(exchange[1], &got)
+	data, _ := os.ReadFile(filepath.Join(vectorsDir, "ACVP-AES-GCM-1.0", "expectedResults.json"))
+	json.Unmarshal(data, &want)
+
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("response:\n%s\nwant:\n%s", gotJSON, wantJSON)
+	}
+}
@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"testing"
+
+	"test-nist/libs"
+)
+
+// TestRunAll counts outcomes per algorithm and group and skips unknown algorithms
+func TestRunAll(t *testing.T) {
+	tests, err := libs.LoadNISTTests(vectorsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := libs.RunAll(tests)
+	if report.OK() {
+		t.Error("report with a failed case is OK")
+	}
+	summary := report.Summary()
+
+	if summary.Passed != 4 || summary.Failed != 1 {
+		t.Errorf("total: got %+v, want 4 passed, 1 failed", summary.Counts)
+	}
+	if len(summary.SkippedSets) != 1 {
+		t.Errorf("skipped: got %v, want the TDES set", summary.SkippedSets)
+	}
+	if len(summary.Algorithms) != 1 || summary.Algorithms[0].Sets != 2 || len(summary.Algorithms[0].Groups) != 3 {
+		t.Fatalf("algorithms: got %+v", summary.Algorithms)
+	}
+	// The exchange set's second case signs the wrong AAD.
+	if group := summary.Algorithms[0].Groups[2]; group.Passed != 1 || group.Failed != 1 {
+		t.Errorf("exchange group: got %+v", group)
+	}
+}